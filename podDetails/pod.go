@@ -152,6 +152,13 @@ func GetServiceNameFromPodDetailsStore(ip string, podDetailsStore *stores.LocalC
 	return serviceName
 }
 
+// GetPodDetailsFromPodDetailsStore returns the details of the pod with the ip. The details are empty if the ip is not
+// in the store.
+func GetPodDetailsFromPodDetailsStore(ip string, podDetailsStore *stores.LocalCacheHSetStore) *PodDetails {
+	workloadDetailsPtr, _ := (*podDetailsStore).Get(ip)
+	return loadPodDetailsIntoHashmap(ip, workloadDetailsPtr)
+}
+
 const (
 	status    = "status"
 	metadata  = "metadata"
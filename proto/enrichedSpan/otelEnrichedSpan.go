@@ -11,6 +11,9 @@ import (
 
 var LogTag = "enrichedSpan"
 
+// SpanEventsKey is the key of the span events in the value store of a span, see GetValueStore
+const SpanEventsKey = "span_events"
+
 type OtelEnrichedRawSpan struct {
 	Span *otlpTrace.Span `json:"span"`
 
@@ -38,6 +41,27 @@ func (x *OtelEnrichedRawSpan) GetProtoEnrichedSpan() *protoSpan.OtelEnrichedRawS
 	return &span
 }
 
+// GetValueStore returns the span attributes along with the span events under SpanEventsKey, to evaluate rules which
+// refer to the events, like `span_events#count(name == exception)`. The events are left out when the span has none or
+// when a span attribute is already named SpanEventsKey, so that `span_events` does not exist for a span without events.
+// The span attributes are not modified.
+func (x *OtelEnrichedRawSpan) GetValueStore() map[string]interface{} {
+	valueStore := make(map[string]interface{}, len(x.SpanAttributes)+1)
+	for key, value := range x.SpanAttributes {
+		valueStore[key] = value
+	}
+
+	if _, ok := valueStore[SpanEventsKey]; ok || len(x.SpanEvents) == 0 {
+		return valueStore
+	}
+	events := make([]interface{}, 0, len(x.SpanEvents))
+	for _, event := range x.SpanEvents {
+		events = append(events, map[string]interface{}(event))
+	}
+	valueStore[SpanEventsKey] = events
+	return valueStore
+}
+
 func ConvertGroupByMapToKVList(groupByMap common.GroupByMap) *protoSpan.KeyValueList {
 	var groupBy []*otlpCommon.KeyValue
 	for k, v := range groupByMap {
@@ -135,7 +159,7 @@ func ConvertListOfKVListToMap(attrMap []*protoSpan.KeyValueList) []common.Generi
 
 func ConvertKVListToMap(attr *protoSpan.KeyValueList) map[string]interface{} {
 	attrMap := map[string]interface{}{}
-	for _, kv := range attr.KeyValueList {
+	for _, kv := range attr.GetKeyValueList() {
 		value := GetAnyValue(kv.Value)
 		if value != nil {
 			attrMap[kv.Key] = value
@@ -175,7 +199,7 @@ func GetAnyValue(value *otlpCommon.AnyValue) interface{} {
 
 func ConvertKVListToGroupByMap(attr *protoSpan.KeyValueList) common.GroupByMap {
 	attrMap := common.GroupByMap{}
-	for _, kv := range attr.KeyValueList {
+	for _, kv := range attr.GetKeyValueList() {
 		value := GetAnyValue(kv.Value)
 		if value != nil {
 			attrMap[common.ScenarioId(kv.Key)] = ConvertToGroupByValues(value)
@@ -1,6 +1,7 @@
 package scenario
 
 import (
+	"fmt"
 	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
 	"github.com/zerok-ai/zk-utils-go/scenario/model"
 )
@@ -24,31 +25,45 @@ func FindMatchingScenarios(workloadIds []string, scenarios map[string]*model.Sce
 	return matchingScenarios, nil
 }
 
+// FindMatchingScenariosForSpans is FindMatchingScenarios for the spans of a trace. Along with workload and filter
+// filters, it evaluates the sequence, count and descendant filters, which need the timing and parentage of the spans.
+func FindMatchingScenariosForSpans(spans []WorkloadSpan, scenarios map[string]*model.Scenario) ([]string, error) {
+	var matchingScenarios []string
+	trace := newTraceSpans(spans)
+	for _, scenario := range scenarios {
+		val, err := evaluateTraceFilter(scenario.Filter, trace.workloadIdMap, trace)
+		if err != nil {
+			zkLogger.Error(LogTag, "Error while evaluating the filter for: ", scenario.Title, err)
+		} else if val {
+			matchingScenarios = append(matchingScenarios, scenario.Title)
+		}
+	}
+	return matchingScenarios, nil
+}
+
 func evaluateFilter(filter model.Filter, workloadIdMap map[string]bool) (bool, error) {
-	defaultValue := getDefaultValue(filter.Condition)
+	return evaluateTraceFilter(filter, workloadIdMap, nil)
+}
+
+// evaluateTraceFilter evaluates the filter. trace is needed only for span filters and can be nil otherwise.
+func evaluateTraceFilter(filter model.Filter, workloadIdMap map[string]bool, trace *traceSpans) (bool, error) {
 	if filter.Type == model.WORKLOAD {
-		for _, workloadId := range *filter.WorkloadIds {
-			val := getValueForWorkload(workloadId, workloadIdMap)
-			if filter.Condition == model.CONDITION_AND && !val {
-				return false, nil
-			} else if filter.Condition == model.CONDITION_OR && val {
-				return true, nil
-			}
-		}
+		workloadIds := *filter.WorkloadIds
+		return filter.Condition.Evaluate(len(workloadIds), func(index int) (bool, error) {
+			return getValueForWorkload(workloadIds[index], workloadIdMap), nil
+		})
 	} else if filter.Type == model.FILTER {
-		for _, f := range *filter.Filters {
-			val, err := evaluateFilter(f, workloadIdMap)
-			if err != nil {
-				return false, err
-			}
-			if filter.Condition == model.CONDITION_AND && !val {
-				return false, nil
-			} else if filter.Condition == model.CONDITION_OR && val {
-				return true, nil
-			}
+		filters := *filter.Filters
+		return filter.Condition.Evaluate(len(filters), func(index int) (bool, error) {
+			return evaluateTraceFilter(filters[index], workloadIdMap, trace)
+		})
+	} else if filter.IsSpanFilter() {
+		if trace == nil {
+			return false, fmt.Errorf("filter of type %s needs the spans of the trace", filter.Type)
 		}
+		return trace.evaluate(filter)
 	}
-	return defaultValue, nil
+	return getDefaultValue(filter.Condition), nil
 }
 
 func getValueForWorkload(workloadId string, workloadIdMap map[string]bool) bool {
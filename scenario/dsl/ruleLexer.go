@@ -0,0 +1,197 @@
+package dsl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenNumber
+	tokenString
+	tokenQuotedWord
+	tokenSymbol
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+	tokenComma
+	tokenColon
+	tokenAssign
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q at position %d", t.text, t.position)
+}
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// specialChars end a word. `#fn(...)` calls inside a word are read up to the matching bracket, so their arguments may
+// contain any of these.
+const specialChars = "()[],:\"`=!<>"
+
+// tokenize splits the input into tokens. Words are attribute names, operators, keywords and datatypes. Words that
+// look like numbers are returned as numbers.
+func tokenize(input string) ([]token, error) {
+	tokens := make([]token, 0)
+	for index := 0; index < len(input); {
+		c := input[index]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			index++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", index})
+			index++
+		case c == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", index})
+			index++
+		case c == '[':
+			tokens = append(tokens, token{tokenLeftBracket, "[", index})
+			index++
+		case c == ']':
+			tokens = append(tokens, token{tokenRightBracket, "]", index})
+			index++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", index})
+			index++
+		case c == ':':
+			tokens = append(tokens, token{tokenColon, ":", index})
+			index++
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			symbol := string(c)
+			if index+1 < len(input) && input[index+1] == '=' {
+				symbol += "="
+			}
+			switch symbol {
+			case "=":
+				tokens = append(tokens, token{tokenAssign, symbol, index})
+			case "!":
+				return nil, fmt.Errorf("unexpected `!` at position %d", index)
+			default:
+				tokens = append(tokens, token{tokenSymbol, symbol, index})
+			}
+			index += len(symbol)
+		case c == '"':
+			end, err := scanQuoted(input, index, '"')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, input[index:end], index})
+			index = end
+		case c == '`':
+			end, err := scanQuoted(input, index, '`')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenQuotedWord, unescapeQuotedWord(input[index+1 : end-1]), index})
+			index = end
+		default:
+			end, err := scanWord(input, index)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenWord
+			if numberPattern.MatchString(input[index:end]) {
+				kind = tokenNumber
+			}
+			tokens = append(tokens, token{kind, input[index:end], index})
+			index = end
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+// scanQuoted returns the index just after the closing quote of the quoted text starting at start
+func scanQuoted(input string, start int, quote byte) (int, error) {
+	for index := start + 1; index < len(input); index++ {
+		switch input[index] {
+		case '\\':
+			index++
+		case quote:
+			return index + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated %c at position %d", quote, start)
+}
+
+// scanWord returns the index just after the word starting at start
+func scanWord(input string, start int) (int, error) {
+	index := start
+	for index < len(input) {
+		c := input[index]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || strings.IndexByte(specialChars, c) >= 0 {
+			break
+		}
+		index++
+
+		if c != '#' {
+			continue
+		}
+
+		// read the function name and the arguments up to the matching bracket
+		for index < len(input) && isNameChar(input[index]) {
+			index++
+		}
+		if index >= len(input) || input[index] != '(' {
+			continue
+		}
+		// brackets in quoted arguments, like `#count(name == ")")`, are skipped
+		depth := 0
+		var quote byte
+		for ; index < len(input); index++ {
+			if quote != 0 {
+				if input[index] == '\\' {
+					index++
+				} else if input[index] == quote {
+					quote = 0
+				}
+			} else if input[index] == '"' || input[index] == '\'' {
+				quote = input[index]
+			} else if input[index] == '(' {
+				depth++
+			} else if input[index] == ')' {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		if depth != 0 {
+			return 0, fmt.Errorf("unterminated function call at position %d", start)
+		}
+		index++
+	}
+	return index, nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func unescapeQuotedWord(text string) string {
+	builder := strings.Builder{}
+	for index := 0; index < len(text); index++ {
+		if text[index] == '\\' && index+1 < len(text) {
+			index++
+		}
+		builder.WriteByte(text[index])
+	}
+	return builder.String()
+}
+
+func escapeQuotedWord(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
@@ -0,0 +1,383 @@
+package dsl
+
+import (
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeString  = "string"
+	typeInteger = "integer"
+	typeFloat   = "float"
+	typeBool    = "bool"
+
+	metadataField    = "field"
+	metadataInput    = "input"
+	metadataJsonPath = "json_path"
+	metadataOptions  = "options"
+)
+
+// symbolOperators are the operators which have a symbol in the dsl. All other operators are written as words.
+var symbolOperators = map[string]string{
+	"==": "equal",
+	"!=": "not_equal",
+	"<":  "less_than",
+	"<=": "less_than_equal",
+	">":  "greater_than",
+	">=": "greater_than_equal",
+}
+
+// ParseRule parses a rule written in the scenario rule dsl into a model.Rule tree. For example:
+//
+//	req_method == "POST" AND (req_path ends_with "/exception" OR status between 500,599)
+//
+// A rule is `attribute[:datatype][metadata] operator [value]`. The datatype is inferred from the value when it is not
+// given: quoted values are strings, numbers with a fraction or exponent are floats, other numbers are integers and
+// true/false are booleans. Values of `in` and `between` are comma separated and may be wrapped in brackets. Attributes
+// which are not plain words can be quoted with backticks. The optional metadata sets the field, input, json_path and
+// match options of the rule, for example `req_path[field="req_path", input="string", options=("ignore_case")]`.
+//
+// Rules are combined with AND and OR, AND binding tighter. A group can also be written as `AND(rule, ...)` or
+// `OR(rule, ...)`, which is how groups with less than two rules are written. Negated groups are always written this
+// way: `NOT(rule)`, `NAND(rule, ...)` and `NOR(rule, ...)`.
+func ParseRule(input string) (model.Rule, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return model.Rule{}, fmt.Errorf("dsl: %v", err)
+	}
+
+	p := &parser{tokens: tokens}
+	rule, err := p.parseExpression()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return model.Rule{}, fmt.Errorf("dsl: %v", err)
+	}
+	return rule, nil
+}
+
+type parser struct {
+	tokens []token
+	index  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.index+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.index+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.index]
+	if t.kind != tokenEOF {
+		p.index++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	t := p.next()
+	if t.kind != kind {
+		return fmt.Errorf("expected `%s`, found %s", text, t)
+	}
+	return nil
+}
+
+func (p *parser) isKeyword(text string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && t.text == text
+}
+
+func (p *parser) parseExpression() (model.Rule, error) {
+	return p.parseCondition(model.OR, p.parseAnd)
+}
+
+func (p *parser) parseAnd() (model.Rule, error) {
+	return p.parseCondition(model.AND, p.parseOperand)
+}
+
+// parseCondition parses operands separated by the condition keyword into a single rule group
+func (p *parser) parseCondition(condition model.Condition, parseOperand func() (model.Rule, error)) (model.Rule, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return model.Rule{}, err
+	}
+
+	rules := model.Rules{first}
+	for p.isKeyword(string(condition)) {
+		p.next()
+		operand, err := parseOperand()
+		if err != nil {
+			return model.Rule{}, err
+		}
+		rules = append(rules, operand)
+	}
+
+	if len(rules) == 1 {
+		return first, nil
+	}
+	return newRuleGroup(condition, rules), nil
+}
+
+func (p *parser) parseOperand() (model.Rule, error) {
+	t := p.peek()
+
+	if t.kind == tokenLeftParen {
+		p.next()
+		rule, err := p.parseExpression()
+		if err != nil {
+			return model.Rule{}, err
+		}
+		return rule, p.expect(tokenRightParen, ")")
+	}
+
+	if t.kind == tokenWord && p.peekAt(1).kind == tokenLeftParen && model.Condition(t.text).IsValid() {
+		return p.parsePrefixGroup()
+	}
+
+	return p.parseLeaf()
+}
+
+// parsePrefixGroup parses `AND(rule, ...)`, `OR(rule, ...)`, `NOT(rule)`, `NAND(rule, ...)` and `NOR(rule, ...)`
+func (p *parser) parsePrefixGroup() (model.Rule, error) {
+	condition := model.Condition(p.next().text)
+	p.next()
+
+	rules := model.Rules{}
+	for p.peek().kind != tokenRightParen {
+		if len(rules) > 0 {
+			if err := p.expect(tokenComma, ","); err != nil {
+				return model.Rule{}, err
+			}
+		}
+		rule, err := p.parseExpression()
+		if err != nil {
+			return model.Rule{}, err
+		}
+		rules = append(rules, rule)
+	}
+	p.next()
+	return newRuleGroup(condition, rules), nil
+}
+
+func (p *parser) parseLeaf() (model.Rule, error) {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenQuotedWord {
+		return model.Rule{}, fmt.Errorf("expected an attribute, found %s", t)
+	}
+	leaf := &model.RuleLeaf{ID: &t.text}
+
+	var datatype *string
+	if p.peek().kind == tokenColon {
+		p.next()
+		typeToken := p.next()
+		if typeToken.kind != tokenWord {
+			return model.Rule{}, fmt.Errorf("expected a datatype, found %s", typeToken)
+		}
+		datatype = &typeToken.text
+	}
+
+	if p.peek().kind == tokenLeftBracket {
+		if err := p.parseMetadata(leaf); err != nil {
+			return model.Rule{}, err
+		}
+	}
+
+	operatorToken := p.next()
+	var operator string
+	switch operatorToken.kind {
+	case tokenSymbol:
+		operator = symbolOperators[operatorToken.text]
+	case tokenWord:
+		operator = operatorToken.text
+	default:
+		return model.Rule{}, fmt.Errorf("expected an operator after %s, found %s", t, operatorToken)
+	}
+
+	values, inferredType, err := p.parseValues()
+	if err != nil {
+		return model.Rule{}, err
+	}
+	if datatype == nil {
+		datatype = &inferredType
+	}
+
+	operatorType := model.OperatorTypes(operator)
+	value := model.ValueTypes(strings.Join(values, ","))
+	dataType := model.DataType(*datatype)
+	leaf.Operator = &operatorType
+	leaf.Value = &value
+	leaf.Datatype = &dataType
+
+	return model.Rule{Type: model.RULE, RuleLeaf: leaf}, nil
+}
+
+// parseValues parses an optional, comma separated list of values, optionally in brackets. It returns the raw values
+// and the datatype inferred from the first value.
+func (p *parser) parseValues() ([]string, string, error) {
+	values := make([]string, 0)
+	inferredType := typeString
+
+	bracketed := p.peek().kind == tokenLeftParen
+	if bracketed {
+		p.next()
+	} else if !isValue(p.peek()) {
+		return []string{""}, inferredType, nil
+	}
+
+	for {
+		t := p.next()
+		if !isValue(t) {
+			return nil, "", fmt.Errorf("expected a value, found %s", t)
+		}
+
+		value, err := valueOf(t)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(values) == 0 {
+			inferredType = inferType(t)
+		}
+		values = append(values, value)
+
+		// continue only if the comma is followed by another value
+		if p.peek().kind != tokenComma || !(bracketed || isValue(p.peekAt(1))) {
+			break
+		}
+		p.next()
+	}
+
+	if bracketed {
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, "", err
+		}
+	}
+	return values, inferredType, nil
+}
+
+// parseMetadata parses `[field="...", input="...", json_path=("...", ...), options=("ignore_case", ...)]`
+func (p *parser) parseMetadata(leaf *model.RuleLeaf) error {
+	p.next()
+	for count := 0; p.peek().kind != tokenRightBracket; count++ {
+		if count > 0 {
+			if err := p.expect(tokenComma, ","); err != nil {
+				return err
+			}
+		}
+
+		key := p.next()
+		if err := p.expect(tokenAssign, "="); err != nil {
+			return err
+		}
+
+		switch key.text {
+		case metadataField, metadataInput:
+			t := p.next()
+			if t.kind != tokenString {
+				return fmt.Errorf("expected a quoted %s, found %s", key.text, t)
+			}
+			value, err := strconv.Unquote(t.text)
+			if err != nil {
+				return fmt.Errorf("invalid string %s: %v", t, err)
+			}
+			if key.text == metadataField {
+				leaf.Field = &value
+			} else {
+				input := model.InputTypes(value)
+				leaf.Input = &input
+			}
+		case metadataJsonPath:
+			jsonPath, err := p.parseStringList()
+			if err != nil {
+				return err
+			}
+			leaf.JsonPath = &jsonPath
+		case metadataOptions:
+			names, err := p.parseStringList()
+			if err != nil {
+				return err
+			}
+			options := model.MatchOptions{}
+			for _, name := range names {
+				if err := options.Set(name); err != nil {
+					return err
+				}
+			}
+			leaf.Options = &options
+		default:
+			return fmt.Errorf("unknown metadata %s", key)
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseStringList() ([]string, error) {
+	if err := p.expect(tokenLeftParen, "("); err != nil {
+		return nil, err
+	}
+	values := make([]string, 0)
+	for p.peek().kind != tokenRightParen {
+		if len(values) > 0 {
+			if err := p.expect(tokenComma, ","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, fmt.Errorf("expected a quoted string, found %s", t)
+		}
+		value, err := strconv.Unquote(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %v", t, err)
+		}
+		values = append(values, value)
+	}
+	p.next()
+	return values, nil
+}
+
+func newRuleGroup(condition model.Condition, rules model.Rules) model.Rule {
+	return model.Rule{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &condition, Rules: rules}}
+}
+
+// isInfixCondition returns true for the conditions that can be written between rules
+func isInfixCondition(text string) bool {
+	return text == string(model.AND) || text == string(model.OR)
+}
+
+func isValue(t token) bool {
+	return t.kind == tokenString || t.kind == tokenNumber || (t.kind == tokenWord && (t.text == "true" || t.text == "false"))
+}
+
+func valueOf(t token) (string, error) {
+	if t.kind != tokenString {
+		return t.text, nil
+	}
+	value, err := strconv.Unquote(t.text)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s: %v", t, err)
+	}
+	return value, nil
+}
+
+func inferType(t token) string {
+	switch t.kind {
+	case tokenNumber:
+		if strings.ContainsAny(t.text, ".eE") {
+			return typeFloat
+		}
+		return typeInteger
+	case tokenWord:
+		return typeBool
+	}
+	return typeString
+}
@@ -0,0 +1,178 @@
+package dsl
+
+import (
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"strconv"
+	"strings"
+)
+
+var (
+	// operatorSymbols is the reverse of symbolOperators
+	operatorSymbols = map[string]string{}
+
+	// listOperators take a comma separated list of values
+	listOperators = map[string]bool{"in": true, "not_in": true, "between": true, "not_between": true, "any_of": true,
+		"all_of": true, "none_of": true, "contains_all": true, "in_cidr": true, "not_in_cidr": true}
+)
+
+func init() {
+	for symbol, operator := range symbolOperators {
+		operatorSymbols[operator] = symbol
+	}
+}
+
+// FormatRule prints the rule in the scenario rule dsl. Parsing the output with ParseRule gives back a rule equal to
+// the input. Every rule leaf must have an id, datatype, operator and value.
+func FormatRule(rule model.Rule) (string, error) {
+	return formatRule(rule)
+}
+
+func formatRule(rule model.Rule) (string, error) {
+	switch rule.Type {
+	case model.RULE_GROUP:
+		return formatRuleGroup(rule)
+	case model.RULE:
+		return formatRuleLeaf(rule)
+	}
+	return "", fmt.Errorf("dsl: unknown rule type %q", rule.Type)
+}
+
+func formatRuleGroup(rule model.Rule) (string, error) {
+	if rule.RuleGroup == nil || rule.Condition == nil {
+		return "", fmt.Errorf("dsl: rule group has no condition")
+	}
+	condition := string(*rule.Condition)
+	if !rule.Condition.IsValid() {
+		return "", fmt.Errorf("dsl: unknown condition %q", condition)
+	}
+
+	rules := make([]string, 0, len(rule.Rules))
+	for _, childRule := range rule.Rules {
+		formatted, err := formatRule(childRule)
+		if err != nil {
+			return "", err
+		}
+		rules = append(rules, formatted)
+	}
+
+	// negated groups and groups with less than two rules cannot be written with infix conditions
+	if len(rules) < 2 || !isInfixCondition(condition) {
+		return condition + "(" + strings.Join(rules, ", ") + ")", nil
+	}
+
+	for index, childRule := range rule.Rules {
+		if childRule.Type == model.RULE_GROUP && len(childRule.Rules) >= 2 && isInfixCondition(string(*childRule.Condition)) {
+			rules[index] = "(" + rules[index] + ")"
+		}
+	}
+	return strings.Join(rules, " "+condition+" "), nil
+}
+
+func formatRuleLeaf(rule model.Rule) (string, error) {
+	leaf := rule.RuleLeaf
+	if leaf == nil || leaf.ID == nil || leaf.Datatype == nil || leaf.Operator == nil || leaf.Value == nil {
+		return "", fmt.Errorf("dsl: rule %v needs an id, datatype, operator and value", rule)
+	}
+	operator := string(*leaf.Operator)
+
+	// value
+	rawValue := string(*leaf.Value)
+	var values []string
+	if listOperators[operator] {
+		values = strings.Split(rawValue, ",")
+	} else if rawValue != "" || (operator != "exists" && operator != "not_exists") {
+		values = []string{rawValue}
+	}
+
+	formattedValues := make([]string, 0, len(values))
+	for _, value := range values {
+		formattedValues = append(formattedValues, formatValue(value))
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(formatAttribute(*leaf.ID))
+
+	// datatype is written only if it is not the one the value implies
+	inferredType := typeString
+	if len(formattedValues) > 0 {
+		tokens, _ := tokenize(formattedValues[0])
+		inferredType = inferType(tokens[0])
+	}
+	if string(*leaf.Datatype) != inferredType {
+		if !isWord(string(*leaf.Datatype)) {
+			return "", fmt.Errorf("dsl: datatype %q cannot be written in the dsl", *leaf.Datatype)
+		}
+		builder.WriteString(":" + string(*leaf.Datatype))
+	}
+
+	metadata := formatMetadata(leaf)
+	if metadata != "" {
+		builder.WriteString(metadata)
+	}
+
+	// operator
+	if symbol, ok := operatorSymbols[operator]; ok {
+		builder.WriteString(" " + symbol)
+	} else if isWord(operator) {
+		builder.WriteString(" " + operator)
+	} else {
+		return "", fmt.Errorf("dsl: operator %q cannot be written in the dsl", operator)
+	}
+
+	if len(formattedValues) > 0 {
+		builder.WriteString(" " + strings.Join(formattedValues, ", "))
+	}
+	return builder.String(), nil
+}
+
+func formatMetadata(leaf *model.RuleLeaf) string {
+	metadata := make([]string, 0)
+	if leaf.Field != nil {
+		metadata = append(metadata, metadataField+"="+strconv.Quote(*leaf.Field))
+	}
+	if leaf.Input != nil {
+		metadata = append(metadata, metadataInput+"="+strconv.Quote(string(*leaf.Input)))
+	}
+	if leaf.JsonPath != nil {
+		jsonPath := make([]string, 0, len(*leaf.JsonPath))
+		for _, path := range *leaf.JsonPath {
+			jsonPath = append(jsonPath, strconv.Quote(path))
+		}
+		metadata = append(metadata, metadataJsonPath+"=("+strings.Join(jsonPath, ", ")+")")
+	}
+	if !leaf.Options.IsEmpty() {
+		options := make([]string, 0)
+		for _, option := range leaf.Options.Names() {
+			options = append(options, strconv.Quote(option))
+		}
+		metadata = append(metadata, metadataOptions+"=("+strings.Join(options, ", ")+")")
+	}
+
+	if len(metadata) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(metadata, ", ") + "]"
+}
+
+// formatAttribute quotes the attribute with backticks unless it reads back as the same plain word
+func formatAttribute(id string) string {
+	if isWord(id) && !model.Condition(id).IsValid() && id != "true" && id != "false" {
+		return id
+	}
+	return "`" + escapeQuotedWord(id) + "`"
+}
+
+// formatValue writes numbers and booleans as they are and quotes everything else
+func formatValue(value string) string {
+	if numberPattern.MatchString(value) || value == "true" || value == "false" {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// isWord returns true if text is read back by the lexer as a single word
+func isWord(text string) bool {
+	tokens, err := tokenize(text)
+	return err == nil && len(tokens) == 2 && tokens[0].kind == tokenWord && tokens[0].text == text
+}
@@ -32,6 +32,10 @@ func (re *BooleanEvaluator) evalRule(rule model.Rule, valueStore map[string]inte
 
 	operator := string(*rule.Operator)
 
+	if IsCollectionOperator(operator) {
+		return compileAndEvalRule(re, rule, re.functionFactory.CompilePath(attributeID, re.attrStoreKey), valueStore)
+	}
+
 	// get the value from the value store
 	value, ok := re.functionFactory.EvaluateString(attributeID, valueStore, re.attrStoreKey)
 
@@ -88,3 +92,68 @@ func getBooleanValue(value interface{}) (bool, error) {
 	}
 	return false, fmt.Errorf("invalid boolean value: %s", strValue)
 }
+
+func (re *BooleanEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	if IsCollectionOperator(string(*rule.Operator)) {
+		return compileCollectionRule(rule, path, boolElements)
+	}
+
+	compiled := compiledBooleanRule{
+		path:     path,
+		operator: string(*rule.Operator),
+	}
+
+	switch compiled.operator {
+	case operatorExists, operatorNotExists:
+	case operatorEqual, operatorNotEqual:
+		valueFromRule, err := getBooleanValue(string(*rule.Value))
+		if err != nil {
+			return nil, err
+		}
+		compiled.value = valueFromRule
+	default:
+		return nil, fmt.Errorf("bool: invalid operator: %s", compiled.operator)
+	}
+	return compiled, nil
+}
+
+type compiledBooleanRule struct {
+	path     functions.CompiledPath
+	operator string
+	value    bool
+}
+
+func (cr compiledBooleanRule) eval(valueStore map[string]interface{}) (bool, error) {
+	defer func() {
+		if r := recover(); r != nil {
+			zkLogger.ErrorF(LoggerTag, "In compiled bool eval: Recovered from panic: %v", r)
+		}
+	}()
+
+	value, ok := cr.path.Evaluate(valueStore)
+
+	switch cr.operator {
+	case operatorExists:
+		return ok && value != nil, nil
+	case operatorNotExists:
+		return !ok || value == nil, nil
+	}
+
+	if !ok {
+		return false, fmt.Errorf("value for attributeName: %s not found in valueStore", cr.path)
+	}
+
+	valueFromStore, isBool := value.(bool)
+	if !isBool {
+		var err error
+		valueFromStore, err = getBooleanValue(value)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if cr.operator == operatorEqual {
+		return cr.value == valueFromStore, nil
+	}
+	return cr.value != valueFromStore, nil
+}
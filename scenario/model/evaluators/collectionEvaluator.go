@@ -0,0 +1,284 @@
+package evaluators
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/ds"
+	logger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	operatorAnyOf             = "any_of"
+	operatorAllOf             = "all_of"
+	operatorNoneOf            = "none_of"
+	operatorContainsAll       = "contains_all"
+	operatorLengthEqual       = "length_equal"
+	operatorLengthGreaterThan = "length_greater_than"
+)
+
+// collectionOperators apply to values which are collections, like a json array extracted from a body. They are
+// supported by every datatype, whose evaluator compares the elements of the collection with the rule values.
+var collectionOperators = []string{operatorAnyOf, operatorAllOf, operatorNoneOf, operatorContainsAll,
+	operatorLengthEqual, operatorLengthGreaterThan}
+
+// IsCollectionOperator returns true for operators which apply to collections
+func IsCollectionOperator(operator string) bool {
+	switch operator {
+	case operatorAnyOf, operatorAllOf, operatorNoneOf, operatorContainsAll, operatorLengthEqual, operatorLengthGreaterThan:
+		return true
+	}
+	return false
+}
+
+// elementType compares the elements of a collection from the value store with the values of a collection rule, as
+// per the datatype of the rule
+type elementType struct {
+	name string
+
+	// parseValue parses a value of the rule
+	parseValue func(value string) (interface{}, error)
+
+	// parseElement converts an element of the collection
+	parseElement func(element interface{}) (interface{}, error)
+
+	// equal returns true if a parsed element matches a parsed rule value. Parsed values are compared with == if nil.
+	equal func(element interface{}, value interface{}) bool
+}
+
+var (
+	stringElements = elementType{
+		name: typeString,
+		parseValue: func(value string) (interface{}, error) {
+			return value, nil
+		},
+		parseElement: func(element interface{}) (interface{}, error) {
+			if str, ok := element.(string); ok {
+				return str, nil
+			}
+			return fmt.Sprintf("%v", element), nil
+		},
+	}
+
+	floatElements = elementType{
+		name: typeFloat,
+		parseValue: func(value string) (interface{}, error) {
+			return strconv.ParseFloat(value, 64)
+		},
+		parseElement: func(element interface{}) (interface{}, error) {
+			return getFloatValue(element)
+		},
+	}
+
+	boolElements = elementType{
+		name: typeBool,
+		parseValue: func(value string) (interface{}, error) {
+			return getBooleanValue(value)
+		},
+		parseElement: func(element interface{}) (interface{}, error) {
+			if value, ok := element.(bool); ok {
+				return value, nil
+			}
+			return getBooleanValue(element)
+		},
+	}
+)
+
+// elements returns the elementType of the measure type
+func (mt measureType) elements() elementType {
+	return elementType{
+		name: mt.name,
+		parseValue: func(value string) (interface{}, error) {
+			return mt.parse(value)
+		},
+		parseElement: func(element interface{}) (interface{}, error) {
+			return mt.fromStore(element)
+		},
+	}
+}
+
+// compileCollectionRule compiles a rule with a collection operator, comparing the elements as per elements
+func compileCollectionRule(rule model.Rule, path functions.CompiledPath, elements elementType) (compiledLeafRule, error) {
+	compiled, err := newCompiledCollectionRule(path, string(*rule.Operator), string(*rule.Value), elements)
+	if err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// newCompiledCollectionRule compiles a rule with a collection operator. The value of the rule is a comma separated
+// list of values for any_of, all_of, none_of and contains_all, and a number for the length operators.
+func newCompiledCollectionRule(path functions.CompiledPath, operator string, value string, elements elementType) (*compiledCollectionRule, error) {
+	compiled := &compiledCollectionRule{path: path, operator: operator, elements: elements}
+
+	switch operator {
+	case operatorLengthEqual, operatorLengthGreaterThan:
+		length, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("%s: invalid length %s for operator %s", elements.name, value, operator)
+		}
+		compiled.length = length
+	case operatorAnyOf, operatorAllOf, operatorNoneOf, operatorContainsAll:
+		for _, part := range strings.Split(value, ",") {
+			parsed, err := elements.parseValue(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("error converting rule value %s to %s: %v", part, elements.name, err)
+			}
+			compiled.values = append(compiled.values, parsed)
+		}
+	default:
+		return nil, fmt.Errorf("%s: invalid collection operator: %s", elements.name, operator)
+	}
+	return compiled, nil
+}
+
+// compiledCollectionRule is a rule with a collection operator. A value which is not a collection is taken as a
+// collection of one element.
+//
+// any_of matches if an element is one of the rule values, all_of if the collection is not empty and every element is
+// one of the rule values, none_of if no element is one of the rule values and contains_all if every rule value is an
+// element of the collection.
+type compiledCollectionRule struct {
+	path     functions.CompiledPath
+	operator string
+	elements elementType
+	values   []interface{}
+	length   int
+}
+
+func (cr *compiledCollectionRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled %s collection eval: Recovered from panic: %v", cr.elements.name, r)
+		}
+	}()
+
+	valueFromStoreI, ok := cr.path.Evaluate(valueStore)
+	if !ok || valueFromStoreI == nil {
+		return false, fmt.Errorf("value for attributeName: %s not found in valueStore", cr.path)
+	}
+	return cr.match(getCollection(valueFromStoreI))
+}
+
+// match applies the operator of the rule to the elements of a collection from the value store
+func (cr *compiledCollectionRule) match(collection []interface{}) (bool, error) {
+	switch cr.operator {
+	case operatorLengthEqual:
+		return len(collection) == cr.length, nil
+	case operatorLengthGreaterThan:
+		return len(collection) > cr.length, nil
+	}
+
+	// elements which cannot be converted to the datatype match none of the rule values
+	elements := make([]interface{}, 0, len(collection))
+	for _, element := range collection {
+		parsed, err := cr.elements.parseElement(element)
+		if err != nil {
+			logger.DebugF(LoggerTag, "%s: ignoring element %v of %s: %v", cr.elements.name, element, cr.path, err)
+			parsed = nil
+		}
+		elements = append(elements, parsed)
+	}
+
+	switch cr.operator {
+	case operatorAnyOf:
+		for _, element := range elements {
+			if cr.isRuleValue(element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case operatorNoneOf:
+		for _, element := range elements {
+			if cr.isRuleValue(element) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case operatorAllOf:
+		for _, element := range elements {
+			if !cr.isRuleValue(element) {
+				return false, nil
+			}
+		}
+		return len(elements) > 0, nil
+	case operatorContainsAll:
+		for _, value := range cr.values {
+			found := false
+			for _, element := range elements {
+				if element != nil && cr.equal(element, value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("%s: invalid collection operator: %s", cr.elements.name, cr.operator)
+}
+
+func (cr *compiledCollectionRule) isRuleValue(element interface{}) bool {
+	if element == nil {
+		return false
+	}
+	for _, value := range cr.values {
+		if cr.equal(element, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cr *compiledCollectionRule) equal(element interface{}, value interface{}) bool {
+	if cr.elements.equal != nil {
+		return cr.elements.equal(element, value)
+	}
+	return element == value
+}
+
+// getCollection returns the elements of a value from the value store. Slices, arrays and json arrays are collections,
+// sets of strings are sorted, and any other value is a collection of one element.
+func getCollection(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		collection := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			collection = append(collection, item)
+		}
+		return collection
+	case ds.Set[string]:
+		items := v.GetAll()
+		sort.Strings(items)
+		return getCollection(items)
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			var collection []interface{}
+			if err := json.Unmarshal([]byte(trimmed), &collection); err == nil {
+				return collection
+			}
+		}
+		return []interface{}{v}
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() == reflect.Slice || reflected.Kind() == reflect.Array {
+		collection := make([]interface{}, 0, reflected.Len())
+		for index := 0; index < reflected.Len(); index++ {
+			collection = append(collection, reflected.Index(index).Interface())
+		}
+		return collection
+	}
+	return []interface{}{value}
+}
@@ -0,0 +1,78 @@
+package evaluators
+
+import (
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+)
+
+// compiledLeafRule is a leaf rule whose attribute path and value have been parsed ahead of evaluation
+type compiledLeafRule interface {
+	eval(valueStore map[string]interface{}) (bool, error)
+}
+
+// CompiledRule is the immutable form of a model.Rule, bound to the AttribStoreKey it was compiled for. Attribute
+// paths are resolved, function chains are built and rule values (numbers, csv lists, regexes) are parsed once in
+// CompileRule, so Eval can be called for every span without re-parsing the rule. A CompiledRule is safe for
+// concurrent use.
+type CompiledRule struct {
+	condition model.Condition
+	rules     []*CompiledRule
+	leaf      compiledLeafRule
+}
+
+// CompileRule compiles the rule for spans carrying attributes as per attrStoreKey
+func (re *RuleEvaluator) CompileRule(rule model.Rule, attrStoreKey cache.AttribStoreKey) (*CompiledRule, error) {
+	return re.compileRule(rule, &attrStoreKey)
+}
+
+func (re *RuleEvaluator) compileRule(rule model.Rule, attrStoreKey *cache.AttribStoreKey) (*CompiledRule, error) {
+
+	if rule.Type == model.RULE_GROUP {
+		if rule.RuleGroup == nil || rule.Condition == nil {
+			return nil, fmt.Errorf("condition is nil")
+		}
+
+		compiledRules := make([]*CompiledRule, 0, len(rule.Rules))
+		for _, childRule := range rule.Rules {
+			compiledChild, err := re.compileRule(childRule, attrStoreKey)
+			if err != nil {
+				return nil, err
+			}
+			compiledRules = append(compiledRules, compiledChild)
+		}
+		return &CompiledRule{condition: *rule.Condition, rules: compiledRules}, nil
+	}
+
+	if rule.RuleLeaf == nil {
+		return nil, fmt.Errorf("rule is nil")
+	}
+	err := re.validate(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	leafEvaluatorType := string(*rule.RuleLeaf.Datatype)
+	ruleEvaluator := re.leafRuleEvaluators[leafEvaluatorType]
+	if ruleEvaluator == nil {
+		return nil, fmt.Errorf("LeafRuleEvaluator not found for type: %s", leafEvaluatorType)
+	}
+
+	path := re.functionFactory.CompilePath(*rule.RuleLeaf.ID, attrStoreKey)
+	leaf, err := ruleEvaluator.compileRule(rule, path)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling rule %s: %v", *rule.RuleLeaf.ID, err)
+	}
+	return &CompiledRule{leaf: leaf}, nil
+}
+
+// Eval evaluates the compiled rule against the attributes of a single span
+func (cr *CompiledRule) Eval(valueStore map[string]interface{}) (bool, error) {
+	if cr.leaf != nil {
+		return cr.leaf.eval(valueStore)
+	}
+
+	return cr.condition.Evaluate(len(cr.rules), func(index int) (bool, error) {
+		return cr.rules[index].Eval(valueStore)
+	})
+}
@@ -36,6 +36,10 @@ func (re *FloatRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]in
 	// get the values assuming that the rule object is valid
 	operator := string(*rule.Operator)
 
+	if IsCollectionOperator(operator) {
+		return compileAndEvalRule(re, rule, re.functionFactory.CompilePath(attributeID, re.attrStoreKey), valueStore)
+	}
+
 	//	switch on operator
 	switch operator {
 
@@ -193,3 +197,139 @@ func (re *FloatRuleEvaluator) valueFromStore(r model.Rule, attributeNameOfID str
 func (re *FloatRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
 	re.attrStoreKey = attrStoreKey
 }
+
+func (re *FloatRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	if IsCollectionOperator(string(*rule.Operator)) {
+		return compileCollectionRule(rule, path, floatElements)
+	}
+
+	compiled := compiledFloatRule{
+		path:     path,
+		operator: string(*rule.Operator),
+	}
+
+	valueFromRule := string(*rule.Value)
+	switch compiled.operator {
+	case operatorExists, operatorNotExists:
+	case operatorLessThan, operatorLessThanEqual, operatorGreaterThan, operatorGreaterThanEqual, operatorEqual, operatorNotEqual:
+		number, err := strconv.ParseFloat(valueFromRule, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error converting rule value %s to float: %v", valueFromRule, err)
+		}
+		compiled.value = number
+	case operatorBetween, operatorNotBetween:
+		compiled.values = re.getValuesFromCSString(valueFromRule)
+		if len(compiled.values) != 2 {
+			return nil, fmt.Errorf("invalid number of values for operator %s: %s", compiled.operator, valueFromRule)
+		}
+	case operatorIn, operatorNotIn:
+		compiled.values = re.getValuesFromCSString(valueFromRule)
+	default:
+		return nil, fmt.Errorf("float: invalid operator: %s", compiled.operator)
+	}
+	return compiled, nil
+}
+
+type compiledFloatRule struct {
+	path     functions.CompiledPath
+	operator string
+	value    float64
+
+	// values holds the range for `between` and the members for `in`
+	values []float64
+}
+
+func (cr compiledFloatRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled float eval: Recovered from panic: %v", r)
+		}
+	}()
+
+	valueInterface, ok := cr.path.Evaluate(valueStore)
+
+	switch cr.operator {
+	case operatorExists:
+		return ok && valueInterface != nil, nil
+	case operatorNotExists:
+		return !ok || valueInterface == nil, nil
+	}
+
+	var valueFromStore float64
+	var err error
+	if !ok || valueInterface == nil {
+		err = fmt.Errorf("value not found for id %s", cr.path)
+	} else {
+		valueFromStore, err = getFloatValue(valueInterface)
+	}
+
+	if err != nil {
+		switch cr.operator {
+		case operatorIn:
+			logger.Error(LoggerTag, "%v", err)
+			return false, nil
+		case operatorNotIn:
+			logger.Error(LoggerTag, "%v", err)
+			return true, nil
+		}
+		return false, err
+	}
+
+	switch cr.operator {
+	case operatorLessThan:
+		return valueFromStore < cr.value, nil
+	case operatorLessThanEqual:
+		return valueFromStore <= cr.value, nil
+	case operatorGreaterThan:
+		return valueFromStore > cr.value, nil
+	case operatorGreaterThanEqual:
+		return valueFromStore >= cr.value, nil
+	case operatorEqual:
+		return valueFromStore == cr.value, nil
+	case operatorNotEqual:
+		return valueFromStore != cr.value, nil
+	case operatorBetween:
+		return valueFromStore >= cr.values[0] && valueFromStore <= cr.values[1], nil
+	case operatorNotBetween:
+		return !(valueFromStore >= cr.values[0] && valueFromStore <= cr.values[1]), nil
+	case operatorIn:
+		return containsFloat(cr.values, valueFromStore), nil
+	case operatorNotIn:
+		return !containsFloat(cr.values, valueFromStore), nil
+	}
+
+	return false, fmt.Errorf("float: invalid operator: %s", cr.operator)
+}
+
+// getFloatValue converts a value from the value store to float64 without going through a string for numeric values
+func getFloatValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	}
+
+	strValue := fmt.Sprintf("%v", value)
+	number, err := strconv.ParseFloat(strValue, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting valueStore value %s to float: %v", strValue, err)
+	}
+	return number, nil
+}
+
+func containsFloat(values []float64, value float64) bool {
+	for _, number := range values {
+		if number == value {
+			return true
+		}
+	}
+	return false
+}
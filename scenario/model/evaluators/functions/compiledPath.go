@@ -0,0 +1,115 @@
+package functions
+
+import (
+	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+)
+
+// compilableFunction is implemented by functions which resolve their arguments against the attribute store on
+// every call. compile does the resolution once and returns a function which only reads the value store.
+type compilableFunction interface {
+	compile() Function
+}
+
+// CompiledPath is an attribute path which has been parsed into a chain of functions bound to an AttribStoreKey.
+// It is immutable and safe to evaluate concurrently.
+type CompiledPath struct {
+	input     string
+	functions []Function
+}
+
+// CompilePath parses inputPath once, resolves the attribute names through the attribute store for attrStoreKey and
+// returns a CompiledPath that can be evaluated against any number of value stores without re-parsing.
+func (ff FunctionFactory) CompilePath(inputPath string, attrStoreKey *cache.AttribStoreKey) CompiledPath {
+	return CompiledPath{
+		input:     inputPath,
+		functions: compileFunctions(ff.GetPathAndFunctions(inputPath, attrStoreKey)),
+	}
+}
+
+func (cp CompiledPath) String() string {
+	return cp.input
+}
+
+// Evaluate returns the value at the path in store. It is the compiled counterpart of FunctionFactory.EvaluateString
+func (cp CompiledPath) Evaluate(store map[string]interface{}) (value interface{}, ok bool) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			zkLogger.ErrorF(LoggerTag, "In CompiledPath.Evaluate: inputPath: %s: Recovered from panic: %v", cp.input, r)
+			value, ok = nil, false
+		}
+	}()
+
+	if len(cp.functions) == 0 {
+		return store, false
+	}
+	return executeFunctions(cp.functions, store)
+}
+
+// EvaluateOnce evaluates the path in store and returns the value along with a CompiledPath which returns the same
+// value for any store, so that a value which is reported can be checked by a rule without evaluating the path again.
+func (cp CompiledPath) EvaluateOnce(store map[string]interface{}) (CompiledPath, interface{}, bool) {
+	value, ok := cp.Evaluate(store)
+	evaluated := CompiledPath{input: cp.input, functions: []Function{evaluatedValue{value: value, ok: ok}}}
+	return evaluated, value, ok
+}
+
+// evaluatedValue is a function which returns a value evaluated earlier, whatever its input
+type evaluatedValue struct {
+	value interface{}
+	ok    bool
+}
+
+func (fn evaluatedValue) Execute(interface{}) (interface{}, bool) {
+	return fn.value, fn.ok
+}
+
+func (fn evaluatedValue) GetName() string {
+	return "evaluatedValue"
+}
+
+func compileFunctions(functionArr []Function) []Function {
+	for index, fn := range functionArr {
+		if compilable, ok := fn.(compilableFunction); ok {
+			functionArr[index] = compilable.compile()
+		}
+	}
+	return functionArr
+}
+
+// ResolveAttribute returns the path that attributeName maps to in the attribute store for attrStoreKey
+func (ff FunctionFactory) ResolveAttribute(attributeName string, attrStoreKey *cache.AttribStoreKey) string {
+	return resolveAttribute(ff.attrStore, attrStoreKey, attributeName)
+}
+
+// ResolvePath returns inputPath with its attributes replaced by the paths they map to in the attribute store for
+// attrStoreKey, as they are resolved when the path is evaluated
+func (ff FunctionFactory) ResolvePath(inputPath string, attrStoreKey *cache.AttribStoreKey) string {
+	path, err := ParsePath(inputPath)
+	if err != nil {
+		return inputPath
+	}
+	resolved := &Path{Elements: make([]PathElement, 0, len(path.Elements))}
+	for _, element := range path.Elements {
+		if element.Call == nil {
+			element.Attribute = resolveAttribute(ff.attrStore, attrStoreKey, element.Attribute)
+		}
+		resolved.Elements = append(resolved.Elements, element)
+	}
+	return resolved.String()
+}
+
+// resolveAttribute returns the path registered against attributeName in the attribute store. If there is none, the
+// attributeName is itself treated as the path.
+func resolveAttribute(attrStore *stores.ExecutorAttrStore, attrStoreKey *cache.AttribStoreKey, attributeName string) string {
+	if attrStoreKey == nil {
+		return attributeName
+	}
+	resolvedVal, ok := attrStore.GetAttributeFromStore(*attrStoreKey, attributeName)
+	if ok {
+		return resolvedVal
+	}
+	return attributeName
+}
@@ -0,0 +1,295 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	lengthFn     = "len"
+	sumFn        = "sum"
+	maxFn        = "max"
+	minFn        = "min"
+	avgFn        = "avg"
+	percentileFn = "percentile"
+	countFn      = "count"
+)
+
+// Aggregate reduces an array, or a string holding a json array, to a number. len returns the number of items of an
+// array or a map, or the number of characters of a string which is not a json array. sum, max, min and avg need
+// numeric items; max, min and avg of an empty array have no value.
+//
+// percentile(p) returns the p-th percentile of the items, for p from 0 to 100, interpolating linearly between the two
+// closest items, so percentile(50) is the median.
+type Aggregate struct {
+	name string
+	args []string
+}
+
+func (fn Aggregate) Execute(valueAtObject interface{}) (interface{}, bool) {
+	if fn.name == lengthFn {
+		return length(valueAtObject)
+	}
+
+	items, ok := toList(valueAtObject)
+	if !ok {
+		return nil, false
+	}
+	numbers := make([]float64, 0, len(items))
+	for _, item := range items {
+		number, err := toNumber(item)
+		if err != nil {
+			zkLogger.ErrorF(LoggerTag, "In %s: %v", fn.name, err)
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+
+	if len(numbers) == 0 {
+		return 0.0, fn.name == sumFn
+	}
+
+	result := numbers[0]
+	switch fn.name {
+	case sumFn, avgFn:
+		result = 0
+		for _, number := range numbers {
+			result += number
+		}
+		if fn.name == avgFn {
+			result /= float64(len(numbers))
+		}
+	case maxFn:
+		for _, number := range numbers {
+			result = math.Max(result, number)
+		}
+	case minFn:
+		for _, number := range numbers {
+			result = math.Min(result, number)
+		}
+	case percentileFn:
+		return percentile(numbers, fn.args[0])
+	}
+	return result, true
+}
+
+func (fn Aggregate) GetName() string {
+	return fn.name
+}
+
+// percentile returns the p-th percentile of the numbers, which are sorted in place
+func percentile(numbers []float64, arg string) (interface{}, bool) {
+	p, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+	if err != nil || p < 0 || p > 100 {
+		zkLogger.ErrorF(LoggerTag, "In %s: percentile %s is not a number from 0 to 100", percentileFn, arg)
+		return nil, false
+	}
+
+	sort.Float64s(numbers)
+	rank := p / 100 * float64(len(numbers)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return numbers[lower] + (numbers[upper]-numbers[lower])*(rank-float64(lower)), true
+}
+
+// CountMatching counts the items of an array, like the span events, which match a filter. The filter is
+// `key == value`, `key != value` or `key` to check that the key is present, for example
+// `span_events#count(name == exception)`. An empty filter counts all the items. Values can be quoted with `"` or `'`.
+//
+// Items are maps. A key which is not in the item is looked up in the `attributes` of the item, if it has any.
+type CountMatching struct {
+	name   string
+	args   []string
+	filter itemFilter
+}
+
+// argItemFilter is the type of the filter argument of count
+var argItemFilter = ArgType{Name: "filter", Validate: func(arg string) error {
+	_, err := parseItemFilter(arg)
+	return err
+}}
+
+func newCountMatching(name string, args []string) CountMatching {
+	filter := itemFilter{}
+	if len(args) > 0 {
+		filter, _ = parseItemFilter(args[0])
+	}
+	return CountMatching{name: name, args: args, filter: filter}
+}
+
+func (fn CountMatching) Execute(valueAtObject interface{}) (interface{}, bool) {
+	items, ok := toList(valueAtObject)
+	if !ok {
+		return nil, false
+	}
+	count := 0
+	for _, item := range items {
+		if fn.filter.matches(item) {
+			count++
+		}
+	}
+	return count, true
+}
+
+func (fn CountMatching) GetName() string {
+	return fn.name
+}
+
+type itemFilter struct {
+	key      string
+	operator string
+	value    string
+}
+
+// parseItemFilter parses the argument of count
+func parseItemFilter(expression string) (itemFilter, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return itemFilter{}, nil
+	}
+
+	for _, operator := range []string{"==", "!="} {
+		key, value, found := strings.Cut(expression, operator)
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), unquote(strings.TrimSpace(value))
+		if key == "" {
+			return itemFilter{}, fmt.Errorf("missing key in count filter %s", expression)
+		}
+		return itemFilter{key: key, operator: operator, value: value}, nil
+	}
+
+	if strings.ContainsAny(expression, " =!<>") {
+		return itemFilter{}, fmt.Errorf("invalid count filter %s, expected `key == value`, `key != value` or `key`", expression)
+	}
+	return itemFilter{key: expression}, nil
+}
+
+func (filter itemFilter) matches(item interface{}) bool {
+	if filter.key == "" {
+		return true
+	}
+
+	value, found := lookupItemKey(item, filter.key)
+	switch filter.operator {
+	case "==":
+		return found && fmt.Sprintf("%v", value) == filter.value
+	case "!=":
+		return !found || fmt.Sprintf("%v", value) != filter.value
+	}
+	return found
+}
+
+func lookupItemKey(item interface{}, key string) (interface{}, bool) {
+	itemMap, ok := toMap(item)
+	if !ok {
+		return nil, false
+	}
+	if value, found := itemMap[key]; found {
+		return value, true
+	}
+	if attributes, ok := toMap(itemMap["attributes"]); ok {
+		value, found := attributes[key]
+		return value, found
+	}
+	return nil, false
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func length(value interface{}) (interface{}, bool) {
+	if str, ok := value.(string); ok {
+		if items, ok := toList(str); ok {
+			return len(items), true
+		}
+		return utf8.RuneCountInString(str), true
+	}
+	if items, ok := toList(value); ok {
+		return len(items), true
+	}
+	if itemMap, ok := toMap(value); ok {
+		return len(itemMap), true
+	}
+	return nil, false
+}
+
+// toList returns the items of a slice, an array or a string holding a json array
+func toList(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if !strings.HasPrefix(trimmed, "[") {
+			return nil, false
+		}
+		var items []interface{}
+		if err := json.Unmarshal([]byte(trimmed), &items); err != nil {
+			return nil, false
+		}
+		return items, true
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Slice && reflected.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]interface{}, 0, reflected.Len())
+	for index := 0; index < reflected.Len(); index++ {
+		items = append(items, reflected.Index(index).Interface())
+	}
+	return items, true
+}
+
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	if itemMap, ok := value.(map[string]interface{}); ok {
+		return itemMap, true
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Map || reflected.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	itemMap := make(map[string]interface{}, reflected.Len())
+	for _, key := range reflected.MapKeys() {
+		itemMap[key.String()] = reflected.MapIndex(key).Interface()
+	}
+	return itemMap, true
+}
+
+func toNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
@@ -0,0 +1,200 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const (
+	headerFn      = "header"
+	cookieFn      = "cookie"
+	contentTypeFn = "contentType"
+)
+
+// Header returns the value of a header, like `req_headers#header(X-Request-Id)`. The headers are a raw HTTP/1.x
+// header block, a json object or a map, and the name of the header is matched ignoring case. A header sent more than
+// once has its values joined with `, `.
+type Header struct {
+	Name string
+	Args []string
+}
+
+func (fn Header) Execute(valueAtObject interface{}) (interface{}, bool) {
+	headers, ok := parseHeaders(valueAtObject)
+	if !ok {
+		return "", false
+	}
+	values, found := headers[strings.ToLower(strings.TrimSpace(fn.Args[0]))]
+	if !found {
+		return "", false
+	}
+	return strings.Join(values, ", "), true
+}
+
+func (fn Header) GetName() string {
+	return fn.Name
+}
+
+// Cookie returns the value of a cookie from the `Cookie` header of a request, or the `Set-Cookie` headers of a
+// response. A cookie with the same name is preferred, then one whose name differs only in case.
+type Cookie struct {
+	Name string
+	Args []string
+}
+
+func (fn Cookie) Execute(valueAtObject interface{}) (interface{}, bool) {
+	headers, ok := parseHeaders(valueAtObject)
+	if !ok {
+		return "", false
+	}
+
+	cookies := make([][2]string, 0)
+	for _, header := range headers["cookie"] {
+		for _, pair := range strings.Split(header, ";") {
+			cookies = appendCookie(cookies, pair)
+		}
+	}
+	for _, header := range headers["set-cookie"] {
+		pair, _, _ := strings.Cut(header, ";")
+		cookies = appendCookie(cookies, pair)
+	}
+
+	name := strings.TrimSpace(fn.Args[0])
+	for _, cookie := range cookies {
+		if cookie[0] == name {
+			return cookie[1], true
+		}
+	}
+	for _, cookie := range cookies {
+		if strings.EqualFold(cookie[0], name) {
+			return cookie[1], true
+		}
+	}
+	return "", false
+}
+
+func (fn Cookie) GetName() string {
+	return fn.Name
+}
+
+func appendCookie(cookies [][2]string, pair string) [][2]string {
+	name, value, found := strings.Cut(pair, "=")
+	if !found {
+		return cookies
+	}
+	return append(cookies, [2]string{strings.TrimSpace(name), unquote(strings.TrimSpace(value))})
+}
+
+// ContentType returns the media type of the `Content-Type` header in lower case, without its parameters, so
+// `Content-Type: application/JSON; charset=utf-8` is `application/json`
+type ContentType struct {
+	Name string
+	Args []string
+}
+
+func (fn ContentType) Execute(valueAtObject interface{}) (interface{}, bool) {
+	headers, ok := parseHeaders(valueAtObject)
+	if !ok {
+		return "", false
+	}
+	values := headers["content-type"]
+	if len(values) == 0 {
+		return "", false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(values[0])
+	if err != nil {
+		mediaType, _, _ = strings.Cut(values[0], ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	}
+	return mediaType, mediaType != ""
+}
+
+func (fn ContentType) GetName() string {
+	return fn.Name
+}
+
+// parseHeaders returns the values of the headers by their name in lower case. Strings holding a json object are parsed
+// as json, and other strings as a raw HTTP/1.x header block, which can start with the request or status line.
+func parseHeaders(value interface{}) (map[string][]string, bool) {
+	switch v := value.(type) {
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") {
+			var headerMap map[string]interface{}
+			if err := json.Unmarshal([]byte(trimmed), &headerMap); err != nil {
+				return nil, false
+			}
+			return headersFromMap(headerMap), true
+		}
+		return parseRawHeaders(v), true
+	case map[string][]string:
+		headers := make(map[string][]string, len(v))
+		for name, values := range v {
+			key := strings.ToLower(name)
+			headers[key] = append(headers[key], values...)
+		}
+		return headers, true
+	}
+
+	headerMap, ok := toMap(value)
+	if !ok {
+		return nil, false
+	}
+	return headersFromMap(headerMap), true
+}
+
+func headersFromMap(headerMap map[string]interface{}) map[string][]string {
+	headers := make(map[string][]string, len(headerMap))
+	for name, value := range headerMap {
+		key := strings.ToLower(name)
+		if items, ok := toList(value); ok && !isString(value) {
+			for _, item := range items {
+				headers[key] = append(headers[key], fmt.Sprintf("%v", item))
+			}
+			continue
+		}
+		if value != nil {
+			headers[key] = append(headers[key], fmt.Sprintf("%v", value))
+		}
+	}
+	return headers
+}
+
+// parseRawHeaders parses `Name: value` lines. A line starting with a space or a tab continues the value of the
+// previous header, and the header block ends at the first empty line after a header.
+func parseRawHeaders(raw string) map[string][]string {
+	headers := make(map[string][]string)
+	lastKey := ""
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			if lastKey != "" {
+				break
+			}
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && lastKey != "" {
+			values := headers[lastKey]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		// lines which are not headers, like `GET / HTTP/1.1`, are skipped
+		name, value, found := strings.Cut(line, ":")
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			lastKey = ""
+			continue
+		}
+		lastKey = strings.ToLower(name)
+		headers[lastKey] = append(headers[lastKey], strings.TrimSpace(value))
+	}
+	return headers
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
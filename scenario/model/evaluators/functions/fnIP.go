@@ -6,10 +6,13 @@ import (
 	"github.com/zerok-ai/zk-utils-go/podDetails"
 	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
 	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+	"net"
 )
 
 const (
 	getWorkloadFromIP = "getWorkloadFromIP"
+	ipToNamespace     = "ipToNamespace"
+	ipToPodName       = "ipToPodName"
 )
 
 type ExtractWorkLoadFromIP struct {
@@ -58,3 +61,136 @@ func (fn ExtractWorkLoadFromIP) transformAttribute(path string, valueAtObject in
 	}
 	return getValueFromStoreInternal(path, valueAtObject.(map[string]interface{}), fn.ff, fn.attrStoreKey, true)
 }
+
+// compile resolves the ip argument once. Any functions it resolves to are executed against the value store on
+// every call
+func (fn ExtractWorkLoadFromIP) compile() Function {
+	if len(fn.args) < 1 {
+		return fn
+	}
+	path := resolveAttribute(fn.attrStore, fn.attrStoreKey, fn.args[0])
+	return compiledExtractWorkLoadFromIP{
+		ExtractWorkLoadFromIP: fn,
+		path:                  path,
+		functions:             compileFunctions(fn.ff.GetPathAndFunctionsInternal(path, fn.attrStoreKey, true)),
+	}
+}
+
+type compiledExtractWorkLoadFromIP struct {
+	ExtractWorkLoadFromIP
+	path      string
+	functions []Function
+}
+
+func (fn compiledExtractWorkLoadFromIP) Execute(valueAtObject interface{}) (interface{}, bool) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			zkLogger.ErrorF(LoggerTag, "In Execute of compiledExtractWorkLoadFromIP: Recovered from panic: %v", r)
+		}
+	}()
+
+	if _, ok := valueAtObject.(map[string]interface{}); !ok {
+		return "", false
+	}
+
+	ip := fn.path
+	newValueAtObject, ok := executeFunctions(fn.functions, valueAtObject)
+	if ok && len(fn.functions) > 0 {
+		ip = fmt.Sprintf("%v", newValueAtObject)
+	}
+
+	// get the workload for the ip
+	serviceName := podDetails.GetServiceNameFromPodDetailsStore(ip, fn.podDetailsStore)
+	return serviceName, true
+}
+
+// PodMetadataFromIP returns a field of the metadata of the pod with the ip, like `dest_ip#ipToNamespace()` for the
+// namespace or `dest_ip#ipToPodName()` for the name of the pod. The port of the ip, if any, is ignored. An ip which
+// does not belong to a known pod has no value.
+//
+// The ip is the value the function is called on, or the optional ip argument, which is a path in the value store like
+// the argument of getWorkloadFromIP, as in `#ipToNamespace(dest_ip)`.
+type PodMetadataFromIP struct {
+	name            string
+	args            []string
+	podDetailsStore *stores.LocalCacheHSetStore
+	attrStore       *stores.ExecutorAttrStore
+	attrStoreKey    *cache.AttribStoreKey
+	ff              *FunctionFactory
+}
+
+func (fn PodMetadataFromIP) Execute(valueAtObject interface{}) (interface{}, bool) {
+	if len(fn.args) > 0 {
+		store, ok := valueAtObject.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		path := resolveAttribute(fn.attrStore, fn.attrStoreKey, fn.args[0])
+		value, ok := getValueFromStoreInternal(path, store, fn.ff, fn.attrStoreKey, true)
+		valueAtObject = fn.ipAtPath(path, value, ok)
+	}
+	return fn.metadata(valueAtObject)
+}
+
+func (fn PodMetadataFromIP) GetName() string {
+	return fn.name
+}
+
+// ipAtPath returns the value at the path of the ip argument or, like getWorkloadFromIP does, the path itself when it
+// has no value
+func (fn PodMetadataFromIP) ipAtPath(path string, value interface{}, ok bool) interface{} {
+	if ok {
+		return value
+	}
+	return path
+}
+
+// compile resolves the ip argument once
+func (fn PodMetadataFromIP) compile() Function {
+	if len(fn.args) < 1 {
+		return fn
+	}
+	path := resolveAttribute(fn.attrStore, fn.attrStoreKey, fn.args[0])
+	return compiledPodMetadataFromIP{
+		PodMetadataFromIP: fn,
+		path:              path,
+		functions:         compileFunctions(fn.ff.GetPathAndFunctionsInternal(path, fn.attrStoreKey, true)),
+	}
+}
+
+type compiledPodMetadataFromIP struct {
+	PodMetadataFromIP
+	path      string
+	functions []Function
+}
+
+func (fn compiledPodMetadataFromIP) Execute(valueAtObject interface{}) (interface{}, bool) {
+	if _, ok := valueAtObject.(map[string]interface{}); !ok {
+		return "", false
+	}
+	value, ok := executeFunctions(fn.functions, valueAtObject)
+	return fn.metadata(fn.ipAtPath(fn.path, value, ok && len(fn.functions) > 0))
+}
+
+// metadata returns the field of the metadata of the pod with the ip
+func (fn PodMetadataFromIP) metadata(ip interface{}) (interface{}, bool) {
+	if ip == nil || fn.podDetailsStore == nil || *fn.podDetailsStore == nil {
+		return "", false
+	}
+
+	address := fmt.Sprintf("%v", ip)
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	metadata := podDetails.GetPodDetailsFromPodDetailsStore(address, fn.podDetailsStore).Metadata
+
+	var value string
+	switch fn.name {
+	case ipToNamespace:
+		value = metadata.Namespace
+	case ipToPodName:
+		value = metadata.PodName
+	}
+	return value, value != ""
+}
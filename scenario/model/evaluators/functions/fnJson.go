@@ -86,3 +86,50 @@ func (fn ExtractJson) transformAttribute(path string, valueAtObject interface{})
 	valueAtObject, ok = getValueFromStoreInternal(path, valueAtObject.(map[string]interface{}), fn.ff, fn.attrStoreKey, true)
 	return path, valueAtObject, ok
 }
+
+// compile resolves the path argument once. The path is pre-compiled unless it resolves to functions, in which case
+// the json path is only known once those functions are executed against the value store
+func (fn ExtractJson) compile() Function {
+	path := resolveAttribute(fn.attrStore, fn.attrStoreKey, fn.args[0])
+	compiled := compiledExtractJson{
+		ExtractJson: fn,
+		functions:   compileFunctions(fn.ff.GetPathAndFunctionsInternal(path, fn.attrStoreKey, true)),
+	}
+
+	jmesPath, err := jmespath.Compile(path)
+	if err != nil {
+		zkLogger.ErrorF(LoggerTag, "Error compiling jmespath at path:%s: %v", path, err)
+	}
+	compiled.jmesPath = jmesPath
+	return compiled
+}
+
+type compiledExtractJson struct {
+	ExtractJson
+	functions []Function
+	jmesPath  *jmespath.JMESPath
+}
+
+func (fn compiledExtractJson) Execute(valueAtObject interface{}) (interface{}, bool) {
+
+	if _, ok := valueAtObject.(map[string]interface{}); !ok {
+		return "", false
+	}
+
+	if len(fn.functions) > 0 {
+		newValueAtObject, ok := executeFunctions(fn.functions, valueAtObject)
+		if ok {
+			return fn.executeJson(fmt.Sprintf("%v", newValueAtObject), valueAtObject)
+		}
+	}
+
+	if fn.jmesPath == nil {
+		return "", false
+	}
+	returnVal, err := fn.jmesPath.Search(valueAtObject)
+	if err != nil {
+		zkLogger.ErrorF(LoggerTag, "Error evaluating jmespath at path:%s for store %v", fn.args[0], valueAtObject)
+		return "", false
+	}
+	return returnVal, true
+}
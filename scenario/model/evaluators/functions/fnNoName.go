@@ -53,3 +53,55 @@ func (fn NoNameFunction) transformAttribute(path string, valueAtObject interface
 	valueAtObject, ok = getValueFromStoreInternal(path, valueAtObject.(map[string]interface{}), fn.ff, fn.attrStoreKey, false)
 	return path, valueAtObject, ok
 }
+
+// compile resolves the attribute once and pre-parses whatever the attribute resolves to
+func (fn NoNameFunction) compile() Function {
+	path := resolveAttribute(fn.attrStore, fn.attrStoreKey, fn.args[0])
+	compiled := compiledNoNameFunction{
+		name:      fn.name,
+		path:      path,
+		functions: compileFunctions(fn.ff.GetPathAndFunctionsInternal(path, fn.attrStoreKey, false)),
+	}
+
+	jmesPath, err := jmespath.Compile(path)
+	if err != nil {
+		zkLogger.ErrorF(LoggerTag, "Error compiling jmespath at path:%s: %v", path, err)
+	}
+	compiled.jmesPath = jmesPath
+	return compiled
+}
+
+type compiledNoNameFunction struct {
+	name      string
+	path      string
+	functions []Function
+	jmesPath  *jmespath.JMESPath
+}
+
+func (fn compiledNoNameFunction) Execute(valueAtObject interface{}) (interface{}, bool) {
+
+	if _, ok := valueAtObject.(map[string]interface{}); !ok {
+		return "", false
+	}
+
+	if len(fn.functions) > 0 {
+		newValueAtObject, ok := executeFunctions(fn.functions, valueAtObject)
+		if ok {
+			return newValueAtObject, true
+		}
+	}
+
+	if fn.jmesPath == nil {
+		return "", false
+	}
+	returnVal, err := fn.jmesPath.Search(valueAtObject)
+	if err != nil {
+		zkLogger.ErrorF(LoggerTag, "Error evaluating jmespath at path:%s for store %v", fn.path, valueAtObject)
+		return "", false
+	}
+	return returnVal, true
+}
+
+func (fn compiledNoNameFunction) GetName() string {
+	return fn.name
+}
@@ -0,0 +1,302 @@
+package functions
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	sqlOperationFn   = "sqlOperation"
+	sqlTablesFn      = "sqlTables"
+	sqlFingerprintFn = "sqlFingerprint"
+	sqlIsWriteFn     = "sqlIsWrite"
+)
+
+// sqlWriteOperations are the operations which change data or schema
+var sqlWriteOperations = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "MERGE": true, "LOAD": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true, "GRANT": true, "REVOKE": true,
+}
+
+// sqlTableKeywords are followed by a list of tables
+var sqlTableKeywords = map[string]bool{"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true}
+
+// sqlClauseKeywords end a list of tables, and are never aliases
+var sqlClauseKeywords = map[string]bool{
+	"WHERE": true, "SET": true, "VALUES": true, "VALUE": true, "SELECT": true, "ON": true, "USING": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "OUTER": true, "CROSS": true, "NATURAL": true,
+	"STRAIGHT_JOIN": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "UNION": true, "FOR": true,
+	"LOCK": true, "WINDOW": true, "PARTITION": true, "USE": true, "FORCE": true, "IGNORE": true, "AS": true,
+	"IF": true, "LIKE": true, "ADD": true, "DUPLICATE": true, "RETURNING": true,
+}
+
+// sqlValueList is a list of placeholders, like the values of an `IN (...)`, which sqlFingerprint collapses to one
+var sqlValueList = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+
+// sqlValueRows is a list of collapsed value lists, like the rows of a multi row `VALUES`, which sqlFingerprint
+// collapses to one row
+var sqlValueRows = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+
+// SqlStatement classifies a SQL statement, like the query of a MySQL span:
+//   - sqlOperation returns the operation in upper case, like SELECT or INSERT. For a `WITH` query, it is the
+//     operation of the main statement.
+//   - sqlTables returns the tables the statement reads or writes, in the order they appear, without quotes.
+//   - sqlFingerprint returns the statement with its literals replaced by `?`, lists of literals and multi row
+//     `VALUES` collapsed to `(?)`, comments removed and white space collapsed, so that the same query with different values has one fingerprint.
+//   - sqlIsWrite returns true for statements which change data or schema, like INSERT, UPDATE or DROP.
+type SqlStatement struct {
+	name string
+	args []string
+}
+
+func (fn SqlStatement) Execute(valueAtObject interface{}) (interface{}, bool) {
+	statement, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	tokens := tokenizeSql(statement)
+	if len(tokens) == 0 {
+		return "", false
+	}
+
+	switch fn.name {
+	case sqlOperationFn:
+		operation := sqlOperation(tokens)
+		return operation, operation != ""
+	case sqlIsWriteFn:
+		return sqlWriteOperations[sqlOperation(tokens)], true
+	case sqlTablesFn:
+		tables := make([]interface{}, 0)
+		for _, table := range sqlTables(tokens) {
+			tables = append(tables, table)
+		}
+		return tables, true
+	case sqlFingerprintFn:
+		return sqlFingerprint(tokens), true
+	}
+	return "", false
+}
+
+func (fn SqlStatement) GetName() string {
+	return fn.name
+}
+
+type sqlTokenKind int
+
+const (
+	sqlWord sqlTokenKind = iota
+	sqlQuotedIdentifier
+	sqlLiteral
+	sqlPunctuation
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+
+	// spaced is true if the token follows white space or a comment
+	spaced bool
+}
+
+func (t sqlToken) keyword() string {
+	if t.kind != sqlWord {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+// tokenizeSql splits a statement into words, quoted identifiers, literals and punctuation, dropping comments. Text in
+// single or double quotes is a string literal, as in MySQL, and text in backquotes is an identifier.
+func tokenizeSql(statement string) []sqlToken {
+	tokens := make([]sqlToken, 0)
+	spaced := false
+	input := []rune(statement)
+	for index := 0; index < len(input); {
+		c := input[index]
+		start := index
+		switch {
+		case unicode.IsSpace(c):
+			index++
+			spaced = true
+			continue
+		case c == '#' || (c == '-' && index+1 < len(input) && input[index+1] == '-' &&
+			(index+2 == len(input) || unicode.IsSpace(input[index+2]))):
+			for index < len(input) && input[index] != '\n' {
+				index++
+			}
+			spaced = true
+			continue
+		case c == '/' && index+1 < len(input) && input[index+1] == '*':
+			index += 2
+			for index < len(input) && !(input[index] == '*' && index+1 < len(input) && input[index+1] == '/') {
+				index++
+			}
+			index += 2
+			spaced = true
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			index++
+			for index < len(input) {
+				if input[index] == '\\' && c != '`' {
+					index += 2
+					continue
+				}
+				if input[index] == c {
+					// a doubled quote is an escaped quote
+					if index+1 < len(input) && input[index+1] == c {
+						index += 2
+						continue
+					}
+					break
+				}
+				index++
+			}
+			index = min(index+1, len(input))
+			if c == '`' {
+				text := strings.ReplaceAll(string(input[start+1:max(start+1, index-1)]), "``", "`")
+				tokens = append(tokens, sqlToken{sqlQuotedIdentifier, text, spaced})
+			} else {
+				tokens = append(tokens, sqlToken{sqlLiteral, string(input[start:index]), spaced})
+			}
+		case unicode.IsDigit(c) || (c == '.' && index+1 < len(input) && unicode.IsDigit(input[index+1]) &&
+			(len(tokens) == 0 || spaced || tokens[len(tokens)-1].kind == sqlPunctuation)):
+			for index < len(input) && (isSqlWordChar(input[index]) || input[index] == '.') {
+				index++
+			}
+			tokens = append(tokens, sqlToken{sqlLiteral, string(input[start:index]), spaced})
+		case isSqlWordChar(c):
+			for index < len(input) && isSqlWordChar(input[index]) {
+				index++
+			}
+			tokens = append(tokens, sqlToken{sqlWord, string(input[start:index]), spaced})
+		default:
+			index++
+			tokens = append(tokens, sqlToken{sqlPunctuation, string(c), spaced})
+		}
+		spaced = false
+	}
+	return tokens
+}
+
+func isSqlWordChar(c rune) bool {
+	return c == '_' || c == '$' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// sqlOperation returns the first keyword of the statement, or of the main statement after the common table
+// expressions of a `WITH`
+func sqlOperation(tokens []sqlToken) string {
+	index := 0
+	for index < len(tokens) && tokens[index].text == "(" {
+		index++
+	}
+	if index >= len(tokens) {
+		return ""
+	}
+	operation := tokens[index].keyword()
+	if operation != "WITH" {
+		return operation
+	}
+
+	depth := 0
+	for _, token := range tokens[index+1:] {
+		switch token.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if keyword := token.keyword(); depth == 0 && (keyword == "SELECT" || sqlWriteOperations[keyword]) {
+			return keyword
+		}
+	}
+	return operation
+}
+
+// sqlTables returns the tables following FROM, JOIN, INTO, UPDATE and TABLE. A list of tables, like
+// `FROM orders o, users AS u`, is read up to the next clause.
+func sqlTables(tokens []sqlToken) []string {
+	tables := make([]string, 0)
+	seen := make(map[string]bool)
+	for index := 0; index < len(tokens); index++ {
+		if !sqlTableKeywords[tokens[index].keyword()] {
+			continue
+		}
+		// `ON DUPLICATE KEY UPDATE` and `FOR UPDATE` are not followed by tables
+		if index > 0 && (tokens[index-1].keyword() == "KEY" || tokens[index-1].keyword() == "FOR") {
+			continue
+		}
+		index++
+		for index < len(tokens) {
+			// skip `IF [NOT] EXISTS` in `CREATE TABLE IF NOT EXISTS t` and the like
+			for index < len(tokens) && (tokens[index].keyword() == "IF" || tokens[index].keyword() == "NOT" || tokens[index].keyword() == "EXISTS") {
+				index++
+			}
+			table, next := readSqlName(tokens, index)
+			if table == "" {
+				break
+			}
+			if !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+
+			// skip the alias, then continue after a `,`
+			if next < len(tokens) && tokens[next].keyword() == "AS" {
+				next++
+			}
+			if next < len(tokens) && isSqlIdentifier(tokens[next]) && !sqlClauseKeywords[tokens[next].keyword()] {
+				next++
+			}
+			if next >= len(tokens) || tokens[next].text != "," || tokens[next].kind != sqlPunctuation {
+				index = next - 1
+				break
+			}
+			index = next + 1
+		}
+	}
+	return tables
+}
+
+// readSqlName reads a name which can be qualified, like `shop`.`orders`, from the token at index. It returns the
+// name, or an empty name for a subquery or a keyword, and the index of the token after it.
+func readSqlName(tokens []sqlToken, index int) (string, int) {
+	parts := make([]string, 0)
+	for index < len(tokens) && isSqlIdentifier(tokens[index]) {
+		if tokens[index].kind == sqlWord && sqlClauseKeywords[tokens[index].keyword()] {
+			break
+		}
+		parts = append(parts, tokens[index].text)
+		index++
+		if index+1 >= len(tokens) || tokens[index].text != "." || tokens[index+1].spaced {
+			break
+		}
+		index++
+	}
+	return strings.Join(parts, "."), index
+}
+
+func isSqlIdentifier(token sqlToken) bool {
+	return token.kind == sqlWord || token.kind == sqlQuotedIdentifier
+}
+
+// sqlFingerprint joins the tokens with a space where the statement had white space or a comment, replacing literals
+// with `?`
+func sqlFingerprint(tokens []sqlToken) string {
+	builder := strings.Builder{}
+	for index, token := range tokens {
+		if token.spaced && index > 0 {
+			builder.WriteByte(' ')
+		}
+		switch token.kind {
+		case sqlLiteral:
+			builder.WriteByte('?')
+		case sqlQuotedIdentifier:
+			builder.WriteString("`" + strings.ReplaceAll(token.text, "`", "``") + "`")
+		default:
+			builder.WriteString(token.text)
+		}
+	}
+	fingerprint := sqlValueList.ReplaceAllString(builder.String(), "(?)")
+	return sqlValueRows.ReplaceAllString(fingerprint, "(?)")
+}
@@ -1,10 +1,20 @@
 package functions
 
-import "strings"
+import (
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 const (
-	toLowerCase = "toLowerCase"
-	toUpperCase = "toUpperCase"
+	toLowerCase    = "toLowerCase"
+	toUpperCase    = "toUpperCase"
+	substringFn    = "substring"
+	splitFn        = "split"
+	regexExtractFn = "regexExtract"
+	trimFn         = "trim"
+	base64DecodeFn = "base64Decode"
 )
 
 type UpperCase struct {
@@ -40,3 +50,172 @@ func (fn LowerCase) Execute(valueAtObject interface{}) (interface{}, bool) {
 func (fn LowerCase) GetName() string {
 	return fn.Name
 }
+
+// Substring returns the characters of a string from start up to end, or up to the end of the string if end is not
+// given. Negative indexes count from the end of the string, and indexes past either end are clamped, so
+// `#substring(0, 3)` of "ab" is "ab".
+type Substring struct {
+	Name  string
+	Args  []string
+	start int
+	end   *int
+}
+
+func newSubstring(name string, args []string) Substring {
+	fn := Substring{Name: name, Args: args}
+	fn.start, _ = strconv.Atoi(strings.TrimSpace(args[0]))
+	if len(args) > 1 {
+		end, _ := strconv.Atoi(strings.TrimSpace(args[1]))
+		fn.end = &end
+	}
+	return fn
+}
+
+func (fn Substring) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	runes := []rune(stringVal)
+	start, end := clampIndex(fn.start, len(runes)), len(runes)
+	if fn.end != nil {
+		end = clampIndex(*fn.end, len(runes))
+	}
+	if start >= end {
+		return "", true
+	}
+	return string(runes[start:end]), true
+}
+
+func (fn Substring) GetName() string {
+	return fn.Name
+}
+
+// clampIndex returns the index in a sequence of length items, counting negative indexes from the end
+func clampIndex(index int, length int) int {
+	if index < 0 {
+		index += length
+	}
+	return max(0, min(index, length))
+}
+
+// Split splits a string around a separator and returns the part at an index, with negative indexes counting from
+// the end, like `req_path#split(/, -1)` for the last segment of a path. Without an index, it returns all the parts.
+type Split struct {
+	Name      string
+	Args      []string
+	separator string
+	index     *int
+}
+
+func newSplit(name string, args []string) Split {
+	fn := Split{Name: name, Args: args, separator: args[0]}
+	if len(args) > 1 {
+		index, _ := strconv.Atoi(strings.TrimSpace(args[1]))
+		fn.index = &index
+	}
+	return fn
+}
+
+func (fn Split) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	parts := strings.Split(stringVal, fn.separator)
+	if fn.index == nil {
+		items := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			items = append(items, part)
+		}
+		return items, true
+	}
+
+	index := *fn.index
+	if index < 0 {
+		index += len(parts)
+	}
+	if index < 0 || index >= len(parts) {
+		return "", false
+	}
+	return parts[index], true
+}
+
+func (fn Split) GetName() string {
+	return fn.Name
+}
+
+// RegexExtract returns a group of the first match of a regex in a string, or the whole match if the group is not
+// given. A string which does not match has no value.
+type RegexExtract struct {
+	Name  string
+	Args  []string
+	regex *regexp.Regexp
+	group int
+}
+
+func newRegexExtract(name string, args []string) RegexExtract {
+	fn := RegexExtract{Name: name, Args: args, regex: regexp.MustCompile(args[0])}
+	if len(args) > 1 {
+		fn.group, _ = strconv.Atoi(strings.TrimSpace(args[1]))
+	}
+	return fn
+}
+
+func (fn RegexExtract) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	match := fn.regex.FindStringSubmatch(stringVal)
+	if match == nil || fn.group < 0 || fn.group >= len(match) {
+		return "", false
+	}
+	return match[fn.group], true
+}
+
+func (fn RegexExtract) GetName() string {
+	return fn.Name
+}
+
+// Trim removes the leading and trailing white space of a string
+type Trim struct {
+	Name string
+	Args []string
+}
+
+func (fn Trim) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if ok {
+		return strings.TrimSpace(stringVal), true
+	}
+	return "", false
+}
+
+func (fn Trim) GetName() string {
+	return fn.Name
+}
+
+// Base64Decode decodes a base64 string, padded or not, in the standard or the url alphabet
+type Base64Decode struct {
+	Name string
+	Args []string
+}
+
+func (fn Base64Decode) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	stringVal = strings.TrimSpace(stringVal)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(stringVal); err == nil {
+			return string(decoded), true
+		}
+	}
+	return "", false
+}
+
+func (fn Base64Decode) GetName() string {
+	return fn.Name
+}
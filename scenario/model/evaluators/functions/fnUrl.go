@@ -0,0 +1,109 @@
+package functions
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	urlPathFn       = "urlPath"
+	urlQueryFn      = "urlQuery"
+	normalizePathFn = "normalizePath"
+
+	// pathIdPlaceholder replaces the id segments of a path in normalizePath
+	pathIdPlaceholder = "{id}"
+)
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// UrlPath returns the path of a url, like `/api/users` for `https://example.com/api/users?id=5`. The value can be a
+// full url or a path with a query, like `req_path`.
+type UrlPath struct {
+	Name string
+	Args []string
+}
+
+func (fn UrlPath) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	return urlPath(stringVal)
+}
+
+func (fn UrlPath) GetName() string {
+	return fn.Name
+}
+
+// UrlQuery returns the value of a query parameter of a url, like `req_path#urlQuery(page)`. The value can also be a
+// query string without the `?`. A parameter which is not in the query has no value.
+type UrlQuery struct {
+	Name string
+	Args []string
+}
+
+func (fn UrlQuery) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+
+	stringVal, _, _ = strings.Cut(stringVal, "#")
+	_, query, found := strings.Cut(stringVal, "?")
+	if !found && !strings.HasPrefix(stringVal, "/") && !strings.Contains(stringVal, "://") {
+		query = stringVal
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil || !values.Has(fn.Args[0]) {
+		return "", false
+	}
+	return values.Get(fn.Args[0]), true
+}
+
+func (fn UrlQuery) GetName() string {
+	return fn.Name
+}
+
+// NormalizePath replaces the numeric and uuid segments of the path of a url with `{id}`, so that
+// `/users/42/orders/0b8e6ad4-6c5e-4b8a-9d4e-2f2a3c1e5f10?full=true` becomes `/users/{id}/orders/{id}`. Rules can then
+// compare and group the requests to the same route.
+type NormalizePath struct {
+	Name string
+	Args []string
+}
+
+func (fn NormalizePath) Execute(valueAtObject interface{}) (interface{}, bool) {
+	stringVal, ok := valueAtObject.(string)
+	if !ok {
+		return "", false
+	}
+	path, ok := urlPath(stringVal)
+	if !ok {
+		return "", false
+	}
+
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		if numericSegment.MatchString(segment) || uuidSegment.MatchString(segment) {
+			segments[index] = pathIdPlaceholder
+		}
+	}
+	return strings.Join(segments, "/"), true
+}
+
+func (fn NormalizePath) GetName() string {
+	return fn.Name
+}
+
+// urlPath returns the path of a url or of a path with a query
+func urlPath(value string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", false
+	}
+	return parsed.Path, true
+}
@@ -1,10 +1,10 @@
 package functions
 
 import (
+	"fmt"
 	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
 	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
 	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
-	"regexp"
 	"strings"
 )
 
@@ -22,6 +22,8 @@ func NewFunctionFactory(podDetailsStore *stores.LocalCacheHSetStore, attrStore *
 	return &FunctionFactory{podDetailsStore: podDetailsStore, attrStore: attrStore}
 }
 
+// GetFunction creates the function called name with the arguments. It returns nil if the function is not registered or
+// the arguments do not match its parameters.
 func (ff FunctionFactory) GetFunction(name string, args []string, attrStoreKey *cache.AttribStoreKey) *Function {
 
 	defer func() {
@@ -32,21 +34,67 @@ func (ff FunctionFactory) GetFunction(name string, args []string, attrStoreKey *
 	}()
 
 	var fn Function
-	switch name {
-	case JsonExtract:
-		fn = ExtractJson{name: name, args: args, attrStore: ff.attrStore, attrStoreKey: attrStoreKey, ff: &ff}
-	case getWorkloadFromIP:
-		fn = ExtractWorkLoadFromIP{name: name, args: args, attrStore: ff.attrStore, attrStoreKey: attrStoreKey, ff: &ff, podDetailsStore: ff.podDetailsStore}
-	case toLowerCase:
-		fn = LowerCase{name, args}
-	case toUpperCase:
-		fn = UpperCase{name, args}
-	default:
+	if name == NoName {
 		fn = NoNameFunction{name: NoName, args: args, attrStore: ff.attrStore, attrStoreKey: attrStoreKey, ff: &ff}
+		return &fn
+	}
+	registered, ok := lookupFunction(name)
+	if !ok {
+		zkLogger.ErrorF(LoggerTag, "In GetFunction: unknown function %s", name)
+		return nil
+	}
+	if err := registered.validateArgs(args); err != nil {
+		zkLogger.ErrorF(LoggerTag, "In GetFunction: %v", err)
+		return nil
+	}
+
+	fn = registered.constructor(args, FunctionContext{
+		PodDetailsStore: ff.podDetailsStore,
+		AttrStore:       ff.attrStore,
+		AttrStoreKey:    attrStoreKey,
+		factory:         &ff,
+	})
+	if fn == nil {
+		return nil
 	}
 	return &fn
 }
 
+// ValidatePath checks that inputPath parses, and that every `#fn(...)` in it calls a registered function with
+// arguments matching its parameters. Unquoted arguments of type path, like `#jsonExtract(a#toLowerCase())`, are
+// validated as paths as well.
+func ValidatePath(inputPath string) error {
+	path, err := ParsePath(inputPath)
+	if err != nil {
+		return fmt.Errorf("%v in %s", err, inputPath)
+	}
+	for _, element := range path.Elements {
+		if element.Call == nil {
+			continue
+		}
+		registered, ok := lookupFunction(element.Call.Name)
+		if !ok {
+			return fmt.Errorf("unknown function %s in %s", element.Call.Name, inputPath)
+		}
+		if err := registered.validateArgs(element.Call.ArgValues()); err != nil {
+			return fmt.Errorf("%v in %s", err, inputPath)
+		}
+		for index, arg := range element.Call.Args {
+			if arg.Quoted || registered.param(index).Type.Name != ArgPath.Name || !strings.Contains(arg.Value, "#") {
+				continue
+			}
+			if err := ValidatePath(arg.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isFunctionNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 func (ff FunctionFactory) HandleStringForFunctions(input string, attrStoreKey *cache.AttribStoreKey) []Function {
 	return ff.GetPathAndFunctionsInternal(input, attrStoreKey, true)
 }
@@ -63,44 +111,30 @@ func (ff FunctionFactory) GetPathAndFunctionsInternal(input string, attrStoreKey
 		}
 	}()
 
-	// Define regular expressions for path, function name, and function parameters
-	// Define the regular expression patternForInput.
-	patternForInput := `([^.#]+(?:\.[^.#]+)*|#[^.)]+\(.*?\))`
-
-	// Define a regular expression pattern to match a function.
-	patternForFunction := `#(\w+)\(([^)]*)\)`
-
-	// Compile the regular expression.
-	compiledRegexFullMatch := regexp.MustCompile(patternForInput)
-	compiledRegexForFunction := regexp.MustCompile(patternForFunction)
-
-	// Find all matches.
-	matches := compiledRegexFullMatch.FindAllString(input, -1)
+	path, err := ParsePath(input)
+	if err != nil {
+		zkLogger.ErrorF(LoggerTag, "In GetPathAndFunctions: %v in %s", err, input)
+		return []Function{}
+	}
 
 	// create the functions
-	functions := make([]Function, 0)
-	for _, match := range matches {
+	functions := make([]Function, 0, len(path.Elements))
+	for _, element := range path.Elements {
 		var fn *Function
-		if strings.HasPrefix(match, "#") {
-			functionMatch := compiledRegexForFunction.FindStringSubmatch(input)
-
-			if len(functionMatch) > 0 {
-				// functionMatch[0] is the full functionMatch, functionMatch[1] is the function name, functionMatch[2] is the arguments.
-				functionName := functionMatch[1]
-				arguments := functionMatch[2]
-
-				// Split arguments into a list.
-				args := strings.Split(arguments, ", ")
-
-				if !allowNoNameFn && functionName == NoName {
-					continue
-				}
-				fn = ff.GetFunction(functionName, args, attrStoreKey)
+		if element.Call != nil {
+			if !allowNoNameFn && element.Call.Name == NoName {
+				continue
 			}
+			fn = ff.GetFunction(element.Call.Name, element.Call.ArgValues(), attrStoreKey)
 		} else if allowNoNameFn {
-			fn = ff.GetFunction(NoName, []string{match}, attrStoreKey)
+			fn = ff.GetFunction(NoName, []string{element.Attribute}, attrStoreKey)
 		}
 
+		// a path with a call which cannot be created has no value, rather than the value of the rest of the chain
+		if fn == nil && element.Call != nil {
+			zkLogger.ErrorF(LoggerTag, "In GetPathAndFunctions: invalid call of %s in %s", element.Call.Name, input)
+			return []Function{}
+		}
 		if fn != nil {
 			functions = append(functions, *fn)
 		}
@@ -121,9 +155,7 @@ func getValueFromStoreInternal(inputPath string, store map[string]interface{}, f
 		}
 	}()
 
-	var ok bool
 	var valueAtObject interface{}
-	var newValueAtObject interface{}
 
 	valueAtObject = store
 	functionArr := ff.GetPathAndFunctionsInternal(inputPath, attrStoreKey, allowNoNameFn)
@@ -131,12 +163,16 @@ func getValueFromStoreInternal(inputPath string, store map[string]interface{}, f
 		return valueAtObject, false
 	}
 
-	// handle functionArr
+	return executeFunctions(functionArr, valueAtObject)
+}
+
+// executeFunctions runs the functions in order, feeding the output of each function to the next one
+func executeFunctions(functionArr []Function, valueAtObject interface{}) (interface{}, bool) {
 	for _, fn := range functionArr {
 		if valueAtObject == nil {
 			return valueAtObject, false
 		}
-		newValueAtObject, ok = fn.Execute(valueAtObject)
+		newValueAtObject, ok := fn.Execute(valueAtObject)
 		if !ok {
 			return valueAtObject, false
 		}
@@ -0,0 +1,196 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+)
+
+type pathTokenKind int
+
+const (
+	pathTokenEOF pathTokenKind = iota
+
+	// pathTokenAttribute is an attribute name or a jmespath, like `req_headers."x-tenant"`
+	pathTokenAttribute
+
+	// pathTokenFunction is the `#name` of a function call
+	pathTokenFunction
+	pathTokenLeftParen
+	pathTokenRightParen
+	pathTokenComma
+	pathTokenDot
+
+	// pathTokenString is a quoted argument. Its text is the unquoted value.
+	pathTokenString
+
+	// pathTokenArgument is an argument which is not quoted, read up to the next `,` or `)` outside brackets and quotes
+	pathTokenArgument
+)
+
+type pathToken struct {
+	kind     pathTokenKind
+	text     string
+	position int
+}
+
+func (t pathToken) String() string {
+	if t.kind == pathTokenEOF {
+		return "end of path"
+	}
+	return fmt.Sprintf("%q at position %d", t.text, t.position)
+}
+
+// pathLexer reads the tokens of an attribute path. Attributes and function arguments are read differently, so the
+// parser tells the lexer which of the two it expects next.
+//
+// An attribute runs up to the next `#` which is not quoted, and keeps its quotes for jmespath, like `"x#y"`. A `.`
+// right before the `#` is not part of the attribute, so `a.#f()` is `a#f()`. Arguments quoted with `"` or `'` are
+// strings, in which `\` escapes the quote and `\` itself, so `"\d+"` is the regex `\d+`. Unquoted arguments run up to
+// the next `,` or `)` outside brackets and quotes, so `a#f(b, c)` is a single argument.
+type pathLexer struct {
+	input    string
+	position int
+}
+
+func (l *pathLexer) skipSpaces() {
+	for l.position < len(l.input) && isSpace(l.input[l.position]) {
+		l.position++
+	}
+}
+
+// nextInPath returns the next token between the elements of a path: an attribute, a function, a `(` or a `.`
+func (l *pathLexer) nextInPath() (pathToken, error) {
+	start := l.position
+	if start >= len(l.input) {
+		return pathToken{pathTokenEOF, "", start}, nil
+	}
+
+	switch c := l.input[start]; c {
+	case '#':
+		l.position++
+		for l.position < len(l.input) && isFunctionNameChar(l.input[l.position]) {
+			l.position++
+		}
+		return pathToken{pathTokenFunction, l.input[start+1 : l.position], start}, nil
+	case '(':
+		l.position++
+		return pathToken{pathTokenLeftParen, "(", start}, nil
+	case '.':
+		l.position++
+		return pathToken{pathTokenDot, ".", start}, nil
+	}
+
+	for l.position < len(l.input) && l.input[l.position] != '#' {
+		switch l.input[l.position] {
+		case '"', '\'', '`':
+			end, err := scanPathQuoted(l.input, l.position)
+			if err != nil {
+				return pathToken{}, err
+			}
+			l.position = end
+		default:
+			l.position++
+		}
+	}
+
+	// the `.` of `a.#f()` separates the attribute from the function
+	text := l.input[start:l.position]
+	if l.position < len(l.input) && strings.HasSuffix(text, ".") {
+		text = text[:len(text)-1]
+	}
+	return pathToken{pathTokenAttribute, text, start}, nil
+}
+
+// nextInArguments returns the next token in the arguments of a function: a `,`, a `)`, a string or an argument
+func (l *pathLexer) nextInArguments() (pathToken, error) {
+	l.skipSpaces()
+	start := l.position
+	if start >= len(l.input) {
+		return pathToken{pathTokenEOF, "", start}, nil
+	}
+
+	switch c := l.input[start]; c {
+	case ',':
+		l.position++
+		return pathToken{pathTokenComma, ",", start}, nil
+	case ')':
+		l.position++
+		return pathToken{pathTokenRightParen, ")", start}, nil
+	case '"', '\'':
+		end, err := scanPathQuoted(l.input, start)
+		if err != nil {
+			return pathToken{}, err
+		}
+		l.position = end
+		return pathToken{pathTokenString, unescapePath(l.input[start+1:end-1], l.input[start]), start}, nil
+	}
+
+	// read up to a `,` or `)` which is not inside brackets or quotes
+	depth := 0
+	for l.position < len(l.input) {
+		c := l.input[l.position]
+		if depth == 0 && (c == ',' || c == ')') {
+			break
+		}
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				return pathToken{}, fmt.Errorf("unbalanced `%c` at position %d", c, l.position)
+			}
+			depth--
+		case '\\':
+			l.position++
+		case '"', '\'', '`':
+			end, err := scanPathQuoted(l.input, l.position)
+			if err != nil {
+				return pathToken{}, err
+			}
+			l.position = end
+			continue
+		}
+		l.position++
+	}
+	if l.position > len(l.input) {
+		return pathToken{}, fmt.Errorf("dangling `\\` at position %d", len(l.input)-1)
+	}
+	return pathToken{pathTokenArgument, strings.TrimRightFunc(l.input[start:l.position], isSpaceRune), start}, nil
+}
+
+// scanPathQuoted returns the index just after the closing quote of the quoted text starting at start
+func scanPathQuoted(input string, start int) (int, error) {
+	quote := input[start]
+	for index := start + 1; index < len(input); index++ {
+		switch input[index] {
+		case '\\':
+			index++
+		case quote:
+			return index + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated %c at position %d", quote, start)
+}
+
+// unescapePath removes the `\` before quote and `\` in a quoted string. Any other `\` is kept.
+func unescapePath(text string, quote byte) string {
+	if !strings.Contains(text, "\\") {
+		return text
+	}
+	builder := strings.Builder{}
+	for index := 0; index < len(text); index++ {
+		if text[index] == '\\' && index+1 < len(text) && (text[index+1] == quote || text[index+1] == '\\') {
+			index++
+		}
+		builder.WriteByte(text[index])
+	}
+	return builder.String()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isSpaceRune(r rune) bool {
+	return r < 128 && isSpace(byte(r))
+}
@@ -0,0 +1,182 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Path is a parsed attribute path, like `req_body#jsonExtract("user.name")#toLowerCase()`. Its elements are
+// evaluated in order, each on the value returned by the previous one.
+type Path struct {
+	Elements []PathElement
+}
+
+// PathElement is either an attribute, which is an attribute name or a jmespath, or a call of a function
+type PathElement struct {
+	Attribute string
+	Call      *Call
+}
+
+// Call is a `#name(args)` call of a registered function
+type Call struct {
+	Name string
+	Args []Arg
+}
+
+// Arg is an argument of a call. Quoted arguments are strings, whose Value has the quotes and escapes removed. An
+// argument which is not quoted, like a nested path `user#toLowerCase()`, is kept as written.
+type Arg struct {
+	Value  string
+	Quoted bool
+}
+
+type parsedPath struct {
+	path *Path
+	err  error
+}
+
+// pathCache holds the parsed paths by input, as the same paths are evaluated for every span
+var pathCache sync.Map
+
+// ParsePath parses an attribute path. Parsed paths are cached and shared, so they must not be modified.
+func ParsePath(input string) (*Path, error) {
+	if cached, ok := pathCache.Load(input); ok {
+		parsed := cached.(parsedPath)
+		return parsed.path, parsed.err
+	}
+	path, err := parsePath(input)
+	pathCache.Store(input, parsedPath{path: path, err: err})
+	return path, err
+}
+
+func parsePath(input string) (*Path, error) {
+	lexer := &pathLexer{input: input}
+	path := &Path{Elements: make([]PathElement, 0)}
+
+	var previous *Call
+	for {
+		tok, err := lexer.nextInPath()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok.kind {
+		case pathTokenEOF:
+			return path, nil
+
+		case pathTokenDot:
+			// a `.` separates a call from the attribute or call following it
+			if previous == nil {
+				return nil, fmt.Errorf("unexpected `.` at position %d", tok.position)
+			}
+			next := *lexer
+			if tok, err = next.nextInPath(); err == nil && tok.kind != pathTokenAttribute && tok.kind != pathTokenFunction {
+				return nil, fmt.Errorf("expected an attribute or a function after `.` at position %d", tok.position)
+			}
+			previous = nil
+
+		case pathTokenAttribute:
+			if previous != nil {
+				return nil, fmt.Errorf("unexpected %s after function %s", tok, previous.Name)
+			}
+			path.Elements = append(path.Elements, PathElement{Attribute: tok.text})
+
+		case pathTokenFunction:
+			if tok.text == "" {
+				return nil, fmt.Errorf("function name missing at position %d", tok.position)
+			}
+			call := &Call{Name: tok.text}
+			if paren, err := lexer.nextInPath(); err != nil || paren.kind != pathTokenLeftParen {
+				return nil, fmt.Errorf("function %s is missing `(` at position %d", call.Name, tok.position+len(tok.text)+1)
+			}
+			if call.Args, err = parseArgs(lexer, call.Name); err != nil {
+				return nil, err
+			}
+			path.Elements = append(path.Elements, PathElement{Call: call})
+			previous = call
+
+		default:
+			return nil, fmt.Errorf("unexpected %s", tok)
+		}
+	}
+}
+
+// parseArgs parses the arguments of a call, after its `(` and up to its `)`
+func parseArgs(lexer *pathLexer, name string) ([]Arg, error) {
+	args := make([]Arg, 0)
+
+	tok, err := lexer.nextInArguments()
+	if err == nil && tok.kind == pathTokenRightParen {
+		return args, nil
+	}
+	for {
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case pathTokenString:
+			args = append(args, Arg{Value: tok.text, Quoted: true})
+		case pathTokenArgument:
+			args = append(args, Arg{Value: tok.text})
+		case pathTokenEOF:
+			return nil, fmt.Errorf("function %s is missing `)`", name)
+		default:
+			return nil, fmt.Errorf("missing argument of function %s at position %d", name, tok.position)
+		}
+
+		if tok, err = lexer.nextInArguments(); err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case pathTokenRightParen:
+			return args, nil
+		case pathTokenComma:
+			tok, err = lexer.nextInArguments()
+		case pathTokenEOF:
+			return nil, fmt.Errorf("function %s is missing `)`", name)
+		default:
+			return nil, fmt.Errorf("unexpected %s in the arguments of function %s", tok, name)
+		}
+	}
+}
+
+// ArgValues returns the values of the arguments of the call
+func (c *Call) ArgValues() []string {
+	values := make([]string, 0, len(c.Args))
+	for _, arg := range c.Args {
+		values = append(values, arg.Value)
+	}
+	return values
+}
+
+// String returns the path in its canonical form, which parses to the same path
+func (p *Path) String() string {
+	builder := strings.Builder{}
+	for index, element := range p.Elements {
+		if element.Call != nil {
+			builder.WriteString(element.Call.String())
+			continue
+		}
+		if index > 0 && p.Elements[index-1].Call != nil {
+			builder.WriteByte('.')
+		}
+		builder.WriteString(element.Attribute)
+	}
+	return builder.String()
+}
+
+func (c *Call) String() string {
+	args := make([]string, 0, len(c.Args))
+	for _, arg := range c.Args {
+		args = append(args, arg.String())
+	}
+	return "#" + c.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (a Arg) String() string {
+	if !a.Quoted {
+		return a.Value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(a.Value) + `"`
+}
@@ -0,0 +1,262 @@
+package functions
+
+import (
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FunctionConstructor creates a function for the arguments of a `#name(args)` call in a rule path. The arguments
+// have been checked against the parameters the function was registered with.
+type FunctionConstructor func(args []string, ctx FunctionContext) Function
+
+// FunctionContext gives a function constructor access to the stores of the FunctionFactory and the AttribStoreKey
+// the path is evaluated for
+type FunctionContext struct {
+	PodDetailsStore *stores.LocalCacheHSetStore
+	AttrStore       *stores.ExecutorAttrStore
+	AttrStoreKey    *cache.AttribStoreKey
+
+	factory *FunctionFactory
+}
+
+// ResolveAttribute returns the path that attributeName maps to in the attribute store
+func (ctx FunctionContext) ResolveAttribute(attributeName string) string {
+	return resolveAttribute(ctx.AttrStore, ctx.AttrStoreKey, attributeName)
+}
+
+// EvaluatePath returns the value at the path in the value store, like FunctionFactory.EvaluateString
+func (ctx FunctionContext) EvaluatePath(path string, valueStore map[string]interface{}) (interface{}, bool) {
+	return getValueFromStoreInternal(path, valueStore, ctx.factory, ctx.AttrStoreKey, true)
+}
+
+// ArgType is the type of a parameter of a function. Validate returns an error if an argument is not of the type.
+type ArgType struct {
+	Name     string
+	Validate func(arg string) error
+}
+
+var (
+	// ArgString is any text
+	ArgString = ArgType{Name: "string", Validate: func(arg string) error { return nil }}
+
+	// ArgPath is an attribute name or a path in the value store
+	ArgPath = ArgType{Name: "path", Validate: func(arg string) error {
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("empty path")
+		}
+		return nil
+	}}
+
+	// ArgInt is an integer
+	ArgInt = ArgType{Name: "int", Validate: func(arg string) error {
+		_, err := strconv.Atoi(strings.TrimSpace(arg))
+		return err
+	}}
+
+	// ArgNumber is an integer or a decimal number
+	ArgNumber = ArgType{Name: "number", Validate: func(arg string) error {
+		_, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		return err
+	}}
+
+	// ArgRegex is a regular expression
+	ArgRegex = ArgType{Name: "regex", Validate: func(arg string) error {
+		_, err := regexp.Compile(arg)
+		return err
+	}}
+)
+
+// Param is a parameter of a function. Optional parameters follow the required ones, and only the last parameter can
+// be variadic, taking any number of arguments.
+type Param struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+	Variadic bool
+}
+
+type registeredFunction struct {
+	name        string
+	constructor FunctionConstructor
+	params      []Param
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]registeredFunction)
+)
+
+// RegisterFunction makes `#name(args)` available in rule paths. The params declare the arity and the types of the
+// arguments, which ValidatePath checks when a scenario is loaded. The built-in functions are registered the same way,
+// and a name can be registered only once.
+func RegisterFunction(name string, ctor FunctionConstructor, params ...Param) error {
+	if name == "" || name == NoName || strings.IndexFunc(name, func(r rune) bool { return r > 127 || !isFunctionNameChar(byte(r)) }) >= 0 {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+	if ctor == nil {
+		return fmt.Errorf("function %s has no constructor", name)
+	}
+	for index, param := range params {
+		if param.Type.Validate == nil {
+			return fmt.Errorf("parameter %s of function %s has no type", param.Name, name)
+		}
+		if param.Variadic && index != len(params)-1 {
+			return fmt.Errorf("parameter %s of function %s is variadic but not the last", param.Name, name)
+		}
+		if index > 0 && params[index-1].Optional && !param.Optional && !param.Variadic {
+			return fmt.Errorf("required parameter %s of function %s follows an optional one", param.Name, name)
+		}
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("function %s is already registered", name)
+	}
+	registry[name] = registeredFunction{name: name, constructor: ctor, params: append([]Param{}, params...)}
+	return nil
+}
+
+func mustRegisterFunction(name string, ctor FunctionConstructor, params ...Param) {
+	if err := RegisterFunction(name, ctor, params...); err != nil {
+		panic(err)
+	}
+}
+
+func lookupFunction(name string) (registeredFunction, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	fn, ok := registry[name]
+	return fn, ok
+}
+
+// validateArgs checks the number and the types of the arguments of a call
+func (fn registeredFunction) validateArgs(args []string) error {
+	required, variadic := 0, false
+	for _, param := range fn.params {
+		if !param.Optional && !param.Variadic {
+			required++
+		}
+		variadic = variadic || param.Variadic
+	}
+	if len(args) < required || (!variadic && len(args) > len(fn.params)) {
+		return fmt.Errorf("function %s takes %s, found %d", fn.name, fn.arity(), len(args))
+	}
+
+	for index, arg := range args {
+		param := fn.param(index)
+		if err := param.Type.Validate(arg); err != nil {
+			return fmt.Errorf("argument %s of function %s must be of type %s: %v", param.Name, fn.name, param.Type.Name, err)
+		}
+	}
+	return nil
+}
+
+// param returns the parameter of the argument at index, which is the variadic parameter past the last one
+func (fn registeredFunction) param(index int) Param {
+	if len(fn.params) == 0 {
+		return Param{}
+	}
+	return fn.params[min(index, len(fn.params)-1)]
+}
+
+func (fn registeredFunction) arity() string {
+	names := make([]string, 0, len(fn.params))
+	for _, param := range fn.params {
+		name := param.Name
+		if param.Variadic {
+			name += "..."
+		} else if param.Optional {
+			name += "?"
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "no arguments"
+	}
+	return "arguments (" + strings.Join(names, ", ") + ")"
+}
+
+func init() {
+	mustRegisterFunction(JsonExtract, func(args []string, ctx FunctionContext) Function {
+		return ExtractJson{name: JsonExtract, args: args, attrStore: ctx.AttrStore, attrStoreKey: ctx.AttrStoreKey, ff: ctx.factory}
+	}, Param{Name: "path", Type: ArgPath})
+
+	mustRegisterFunction(getWorkloadFromIP, func(args []string, ctx FunctionContext) Function {
+		return ExtractWorkLoadFromIP{name: getWorkloadFromIP, args: args, attrStore: ctx.AttrStore, attrStoreKey: ctx.AttrStoreKey, ff: ctx.factory, podDetailsStore: ctx.PodDetailsStore}
+	}, Param{Name: "ip", Type: ArgPath})
+	for _, name := range []string{ipToNamespace, ipToPodName} {
+		name := name
+		mustRegisterFunction(name, func(args []string, ctx FunctionContext) Function {
+			return PodMetadataFromIP{name: name, args: args, podDetailsStore: ctx.PodDetailsStore, attrStore: ctx.AttrStore, attrStoreKey: ctx.AttrStoreKey, ff: ctx.factory}
+		}, Param{Name: "ip", Type: ArgPath, Optional: true})
+	}
+
+	mustRegisterFunction(toLowerCase, func(args []string, ctx FunctionContext) Function {
+		return LowerCase{toLowerCase, args}
+	})
+	mustRegisterFunction(toUpperCase, func(args []string, ctx FunctionContext) Function {
+		return UpperCase{toUpperCase, args}
+	})
+
+	mustRegisterFunction(substringFn, func(args []string, ctx FunctionContext) Function {
+		return newSubstring(substringFn, args)
+	}, Param{Name: "start", Type: ArgInt}, Param{Name: "end", Type: ArgInt, Optional: true})
+	mustRegisterFunction(splitFn, func(args []string, ctx FunctionContext) Function {
+		return newSplit(splitFn, args)
+	}, Param{Name: "separator", Type: ArgString}, Param{Name: "index", Type: ArgInt, Optional: true})
+	mustRegisterFunction(regexExtractFn, func(args []string, ctx FunctionContext) Function {
+		return newRegexExtract(regexExtractFn, args)
+	}, Param{Name: "pattern", Type: ArgRegex}, Param{Name: "group", Type: ArgInt, Optional: true})
+	mustRegisterFunction(trimFn, func(args []string, ctx FunctionContext) Function {
+		return Trim{trimFn, args}
+	})
+	mustRegisterFunction(base64DecodeFn, func(args []string, ctx FunctionContext) Function {
+		return Base64Decode{base64DecodeFn, args}
+	})
+
+	mustRegisterFunction(urlPathFn, func(args []string, ctx FunctionContext) Function {
+		return UrlPath{urlPathFn, args}
+	})
+	mustRegisterFunction(urlQueryFn, func(args []string, ctx FunctionContext) Function {
+		return UrlQuery{urlQueryFn, args}
+	}, Param{Name: "name", Type: ArgString})
+	mustRegisterFunction(normalizePathFn, func(args []string, ctx FunctionContext) Function {
+		return NormalizePath{normalizePathFn, args}
+	})
+
+	mustRegisterFunction(headerFn, func(args []string, ctx FunctionContext) Function {
+		return Header{headerFn, args}
+	}, Param{Name: "name", Type: ArgString})
+	mustRegisterFunction(cookieFn, func(args []string, ctx FunctionContext) Function {
+		return Cookie{cookieFn, args}
+	}, Param{Name: "name", Type: ArgString})
+	mustRegisterFunction(contentTypeFn, func(args []string, ctx FunctionContext) Function {
+		return ContentType{contentTypeFn, args}
+	})
+
+	for _, name := range []string{sqlOperationFn, sqlTablesFn, sqlFingerprintFn, sqlIsWriteFn} {
+		name := name
+		mustRegisterFunction(name, func(args []string, ctx FunctionContext) Function {
+			return SqlStatement{name: name, args: args}
+		})
+	}
+
+	for _, name := range []string{lengthFn, sumFn, maxFn, minFn, avgFn} {
+		name := name
+		mustRegisterFunction(name, func(args []string, ctx FunctionContext) Function {
+			return Aggregate{name: name, args: args}
+		})
+	}
+	mustRegisterFunction(percentileFn, func(args []string, ctx FunctionContext) Function {
+		return Aggregate{name: percentileFn, args: args}
+	}, Param{Name: "p", Type: ArgNumber})
+	mustRegisterFunction(countFn, func(args []string, ctx FunctionContext) Function {
+		return newCountMatching(countFn, args)
+	}, Param{Name: "filter", Type: argItemFilter, Optional: true})
+}
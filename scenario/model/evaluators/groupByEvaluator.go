@@ -0,0 +1,104 @@
+package evaluators
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/crypto"
+	"github.com/zerok-ai/zk-utils-go/proto/enrichedSpan"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+	"sort"
+)
+
+// GroupByEvaluator computes the group by values of scenarios for spans. The Title and Hash of a model.GroupBy are
+// attribute expressions, like the ids of rules, and are resolved against the span's value store.
+type GroupByEvaluator struct {
+	functionFactory *functions.FunctionFactory
+}
+
+func NewGroupByEvaluator(executorAttrStore *stores.ExecutorAttrStore, podDetailsStore *stores.LocalCacheHSetStore) *GroupByEvaluator {
+	return &GroupByEvaluator{functionFactory: functions.NewFunctionFactory(podDetailsStore, executorAttrStore)}
+}
+
+// EvaluateGroupBy returns the group by values of the scenario for a span matched by the workload. Expressions which
+// are not found in the value store resolve to an empty string.
+func (ge *GroupByEvaluator) EvaluateGroupBy(scenario model.Scenario, workloadId string, attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) common.GroupByValues {
+	groupByValues := make(common.GroupByValues, 0)
+	for _, groupBy := range scenario.GroupBy {
+		if groupBy.WorkloadId != workloadId {
+			continue
+		}
+		groupByValues = append(groupByValues, &common.GroupByValueItem{
+			WorkloadId: workloadId,
+			Title:      ge.evaluateExpression(groupBy.Title, attrStoreKey, valueStore),
+			Hash:       ge.evaluateExpression(groupBy.Hash, attrStoreKey, valueStore),
+		})
+	}
+	return groupByValues
+}
+
+// FillGroupBy sets the group by values of the scenarios for the workloads in span.WorkloadIdList in span.GroupBy.
+// Scenarios without group by values for the workloads are left out.
+func (ge *GroupByEvaluator) FillGroupBy(span *enrichedSpan.OtelEnrichedRawSpan, scenarios map[string]*model.Scenario, attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) {
+	for _, scenario := range scenarios {
+		if scenario == nil {
+			continue
+		}
+
+		groupByValues := make(common.GroupByValues, 0)
+		for _, workloadId := range span.WorkloadIdList {
+			groupByValues = append(groupByValues, ge.EvaluateGroupBy(*scenario, workloadId, attrStoreKey, valueStore)...)
+		}
+		if len(groupByValues) == 0 {
+			continue
+		}
+
+		if span.GroupBy == nil {
+			span.GroupBy = common.GroupByMap{}
+		}
+		span.GroupBy[common.ScenarioId(scenario.Id)] = groupByValues
+	}
+}
+
+// GroupKey returns a key which is the same for group by values with the same items, in any order
+func GroupKey(groupByValues common.GroupByValues) string {
+	items := make([]common.GroupByValueItem, 0, len(groupByValues))
+	for _, item := range groupByValues {
+		if item != nil {
+			items = append(items, *item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].WorkloadId != items[j].WorkloadId {
+			return items[i].WorkloadId < items[j].WorkloadId
+		}
+		if items[i].Title != items[j].Title {
+			return items[i].Title < items[j].Title
+		}
+		return items[i].Hash < items[j].Hash
+	})
+
+	jStr, _ := json.Marshal(items)
+	return crypto.CalculateHashNewSHA2(string(jStr)).String()
+}
+
+func (ge *GroupByEvaluator) evaluateExpression(expression string, attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) string {
+	value, ok := ge.functionFactory.EvaluateString(expression, valueStore, &attrStoreKey)
+	if !ok || value == nil {
+		return ""
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		jStr, err := json.Marshal(v)
+		if err == nil {
+			return string(jStr)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
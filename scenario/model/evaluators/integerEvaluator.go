@@ -198,3 +198,8 @@ func (re *IntegerRuleEvaluator) valueFromStore(r model.Rule, attributeNameOfID s
 func (re *IntegerRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
 	re.attrStoreKey = attrStoreKey
 }
+
+// compileRule compiles the rule as a float rule, as RuleEvaluator evaluates integer rules with FloatRuleEvaluator
+func (re *IntegerRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	return NewFloatRuleEvaluator(re.functionFactory).compileRule(rule, path)
+}
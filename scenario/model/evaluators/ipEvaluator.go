@@ -0,0 +1,208 @@
+package evaluators
+
+import (
+	"fmt"
+	logger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"net/netip"
+	"strings"
+)
+
+const (
+	typeIP = "ip"
+
+	operatorInCidr    = "in_cidr"
+	operatorNotInCidr = "not_in_cidr"
+)
+
+var ipOperators = []string{operatorExists, operatorNotExists, operatorEqual, operatorNotEqual, operatorIn, operatorNotIn,
+	operatorInCidr, operatorNotInCidr}
+
+// IPRuleEvaluator evaluates rules on IPv4 and IPv6 addresses, like the source ip of a request. The value in the store
+// can have a port, like `10.0.0.1:8080` or `[fd00::1]:443`, and IPv4 addresses mapped to IPv6, like
+// `::ffff:10.0.0.1`, are the same as the IPv4 address.
+//
+// equal, not_equal, in and not_in compare the addresses. in_cidr and not_in_cidr take a comma separated list of
+// ranges, like `10.0.0.0/8,fd00::/8`, where an address without a prefix length is a range of one address. The
+// collection operators compare each address of a list.
+type IPRuleEvaluator struct {
+	functionFactory *functions.FunctionFactory
+	attrStoreKey    *cache.AttribStoreKey
+}
+
+func (re *IPRuleEvaluator) init() LeafRuleEvaluator {
+	return re
+}
+
+func NewIPRuleEvaluator(functionFactory *functions.FunctionFactory) LeafRuleEvaluator {
+	return (&IPRuleEvaluator{functionFactory: functionFactory}).init()
+}
+
+func (re *IPRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]interface{}) (bool, error) {
+	compiled, err := re.compileRule(rule, re.functionFactory.CompilePath(*rule.RuleLeaf.ID, re.attrStoreKey))
+	if err != nil {
+		return false, err
+	}
+	return compiled.eval(valueStore)
+}
+
+func (re *IPRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
+	re.attrStoreKey = attrStoreKey
+}
+
+func (re *IPRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	operator := string(*rule.Operator)
+	value := string(*rule.Value)
+	if IsCollectionOperator(operator) {
+		return compileCollectionRule(rule, path, ipElements)
+	}
+
+	compiled := compiledIPRule{path: path, operator: operator}
+	switch operator {
+	case operatorExists, operatorNotExists:
+	case operatorEqual, operatorNotEqual, operatorIn, operatorNotIn:
+		for _, part := range strings.Split(value, ",") {
+			addr, err := parseIP(part)
+			if err != nil {
+				return nil, fmt.Errorf("ip: invalid value %s: %v", part, err)
+			}
+			compiled.addrs = append(compiled.addrs, addr)
+		}
+		if (operator == operatorEqual || operator == operatorNotEqual) && len(compiled.addrs) > 1 {
+			return nil, fmt.Errorf("ip: operator %s takes a single address, found %s", operator, value)
+		}
+	case operatorInCidr, operatorNotInCidr:
+		ranges, err := parseIPRanges(value)
+		if err != nil {
+			return nil, fmt.Errorf("ip: %v", err)
+		}
+		compiled.ranges = ranges
+	default:
+		return nil, fmt.Errorf("ip: invalid operator: %s", operator)
+	}
+	return compiled, nil
+}
+
+type compiledIPRule struct {
+	path     functions.CompiledPath
+	operator string
+	addrs    []netip.Addr
+	ranges   ipRanges
+}
+
+func (cr compiledIPRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled ip eval: Recovered from panic: %v", r)
+		}
+	}()
+
+	valueFromStoreI, ok := cr.path.Evaluate(valueStore)
+	if !ok || valueFromStoreI == nil {
+		switch cr.operator {
+		case operatorExists:
+			return false, nil
+		case operatorNotExists:
+			return true, nil
+		}
+		return false, fmt.Errorf("value for attributeName: %s not found in valueStore", cr.path)
+	}
+
+	switch cr.operator {
+	case operatorExists:
+		return true, nil
+	case operatorNotExists:
+		return false, nil
+	}
+
+	addr, err := parseIP(fmt.Sprintf("%v", valueFromStoreI))
+	if err != nil {
+		return false, fmt.Errorf("ip: %s: %v", cr.path, err)
+	}
+
+	switch cr.operator {
+	case operatorInCidr:
+		return cr.ranges.contains(addr), nil
+	case operatorNotInCidr:
+		return !cr.ranges.contains(addr), nil
+	}
+
+	matched := false
+	for _, value := range cr.addrs {
+		if value == addr {
+			matched = true
+			break
+		}
+	}
+	if cr.operator == operatorNotEqual || cr.operator == operatorNotIn {
+		return !matched, nil
+	}
+	return matched, nil
+}
+
+// ipElements is the elementType of lists of ip addresses
+var ipElements = elementType{
+	name: typeIP,
+	parseValue: func(value string) (interface{}, error) {
+		return parseIP(value)
+	},
+	parseElement: func(element interface{}) (interface{}, error) {
+		return parseIP(fmt.Sprintf("%v", element))
+	},
+}
+
+// parseIP parses an IPv4 or IPv6 address, ignoring the port and unmapping IPv4 addresses mapped to IPv6
+func parseIP(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		addrPort, portErr := netip.ParseAddrPort(value)
+		if portErr != nil {
+			return netip.Addr{}, err
+		}
+		addr = addrPort.Addr()
+	}
+	return addr.WithZone("").Unmap(), nil
+}
+
+// ipRanges is a list of address ranges, like the value of an in_cidr rule
+type ipRanges []netip.Prefix
+
+// parseIPRanges parses a comma separated list of ranges in CIDR notation. An address without a prefix length is a
+// range of one address.
+func parseIPRanges(value string) (ipRanges, error) {
+	ranges := make(ipRanges, 0)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.Contains(part, "/") {
+			addr, err := parseIP(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %s: %v", part, err)
+			}
+			ranges = append(ranges, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s: %v", part, err)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		ranges = append(ranges, prefix.Masked())
+	}
+	return ranges, nil
+}
+
+func (ranges ipRanges) contains(addr netip.Addr) bool {
+	for _, prefix := range ranges {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package evaluators
+
+import (
+	"encoding/json"
+	"fmt"
+	logger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"strings"
+)
+
+// keyValueSeparator separates the key from the value in the value of a key-value rule
+const keyValueSeparator = ":"
+
+// KeyValueRuleEvaluator evaluates rules on maps, like the headers of a request. The value of a rule is
+// `key:value`, for example `X-Tenant:acme-.*` with the matches operator. The key is looked up ignoring case, and the
+// string operators are applied to its value. exists and not_exists take only the key and check if it is in the map.
+//
+// A key with a list of values, like a header sent more than once, matches if any of its values matches. The negated
+// operators, like not_equal, match if none of the values matches the positive operator. A missing key matches only
+// the negated operators. The match options of the rule apply to the values, not to the key.
+//
+// The collection operators apply to the values of the key, like `Accept:text/html,application/json` with any_of. A
+// missing key has no values.
+type KeyValueRuleEvaluator struct {
+	functionFactory *functions.FunctionFactory
+	attrStoreKey    *cache.AttribStoreKey
+}
+
+func (re *KeyValueRuleEvaluator) init() LeafRuleEvaluator {
+	return re
+}
+
+func NewKeyValueRuleEvaluator(functionFactory *functions.FunctionFactory) LeafRuleEvaluator {
+	return (&KeyValueRuleEvaluator{functionFactory: functionFactory}).init()
+}
+
+func (re *KeyValueRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]interface{}) (bool, error) {
+	compiled, err := re.compileRule(rule, re.functionFactory.CompilePath(*rule.RuleLeaf.ID, re.attrStoreKey))
+	if err != nil {
+		return false, err
+	}
+	return compiled.eval(valueStore)
+}
+
+func (re *KeyValueRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
+	re.attrStoreKey = attrStoreKey
+}
+
+func (re *KeyValueRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	operator := string(*rule.Operator)
+	value := string(*rule.Value)
+
+	if operator == operatorExists || operator == operatorNotExists {
+		if value == "" {
+			return nil, fmt.Errorf("key-value: operator %s needs a key", operator)
+		}
+		return compiledKeyValueRule{path: path, operator: operator, key: value}, nil
+	}
+
+	key, valueFromRule, found := strings.Cut(value, keyValueSeparator)
+	if !found || key == "" {
+		return nil, fmt.Errorf("key-value: value %q is not of the form key%svalue", value, keyValueSeparator)
+	}
+
+	if IsCollectionOperator(operator) {
+		collectionRule, err := newCompiledCollectionRule(path, operator, valueFromRule, stringElementsFor(rule.Options))
+		if err != nil {
+			return nil, fmt.Errorf("key-value: %v", err)
+		}
+		return compiledKeyValueRule{path: path, operator: operator, key: key, collectionRule: collectionRule}, nil
+	}
+
+	valueRule, err := newCompiledStringRule(path, operator, valueFromRule, rule.Options)
+	if err != nil {
+		return nil, fmt.Errorf("key-value: %v", err)
+	}
+	return compiledKeyValueRule{path: path, operator: operator, key: key, valueRule: valueRule}, nil
+}
+
+type compiledKeyValueRule struct {
+	path           functions.CompiledPath
+	operator       string
+	key            string
+	valueRule      compiledStringRule
+	collectionRule *compiledCollectionRule
+}
+
+func (cr compiledKeyValueRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled key-value eval: Recovered from panic: %v", r)
+		}
+	}()
+
+	valueFromStoreI, ok := cr.path.Evaluate(valueStore)
+	if !ok || valueFromStoreI == nil {
+		if cr.operator == operatorExists {
+			return false, nil
+		}
+		if cr.operator == operatorNotExists {
+			return true, nil
+		}
+		return false, fmt.Errorf("value for attributeName: %s not found in valueStore", cr.path)
+	}
+
+	keyValues, err := getKeyValueMap(valueFromStoreI)
+	if err != nil {
+		return false, fmt.Errorf("key-value: %s: %v", cr.path, err)
+	}
+
+	values, found := lookupKey(keyValues, cr.key)
+	switch cr.operator {
+	case operatorExists:
+		return found, nil
+	case operatorNotExists:
+		return !found, nil
+	}
+
+	if cr.collectionRule != nil {
+		collection := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			collection = append(collection, value)
+		}
+		return cr.collectionRule.match(collection)
+	}
+
+	negated := isNegatedOperator(cr.operator)
+	for _, value := range values {
+		matched, err := cr.valueRule.match(value)
+		if err != nil {
+			return false, err
+		}
+		if negated && !matched {
+			return false, nil
+		}
+		if !negated && matched {
+			return true, nil
+		}
+	}
+	return negated, nil
+}
+
+// getKeyValueMap converts a value from the value store to a map. Maps are used as is and strings are parsed as json
+// objects.
+func getKeyValueMap(value interface{}) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case map[string]string:
+		keyValues := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			keyValues[key] = item
+		}
+		return keyValues, nil
+	case string:
+		var keyValues map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &keyValues); err != nil {
+			return nil, fmt.Errorf("value is not a json object: %v", err)
+		}
+		return keyValues, nil
+	}
+	return nil, fmt.Errorf("value of type %T is not a map", value)
+}
+
+// lookupKey returns the values of key in the map, ignoring the case of the key. A key with a list has a value per
+// item of the list.
+func lookupKey(keyValues map[string]interface{}, key string) ([]string, bool) {
+	value, found := keyValues[key]
+	if !found {
+		for k, v := range keyValues {
+			if strings.EqualFold(k, key) {
+				value, found = v, true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+		return values, true
+	case nil:
+		return []string{}, true
+	}
+	return []string{fmt.Sprintf("%v", value)}, true
+}
+
+// isNegatedOperator returns true for the string operators which match when the positive operator does not
+func isNegatedOperator(operator string) bool {
+	switch operator {
+	case operatorNotEqual, operatorDoesNotMatch, operatorDoesNotContain, operatorNotIn, operatorDoesNotBeginWith,
+		operatorDoesNotEndWith:
+		return true
+	}
+	return false
+}
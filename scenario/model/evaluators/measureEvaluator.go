@@ -0,0 +1,288 @@
+package evaluators
+
+import (
+	"fmt"
+	logger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// measureType is a datatype whose values are quantities with a unit, like durations, which are compared as int64
+type measureType struct {
+	name string
+
+	// parse converts a rule value, or a string from the value store, to the quantity
+	parse func(value string) (int64, error)
+}
+
+var (
+	// durations are compared in nanoseconds. Values are go durations like `250ms`, or numbers of nanoseconds.
+	durationType = measureType{name: typeDuration, parse: parseDuration}
+
+	// timestamps are compared in nanoseconds since the epoch. Values are RFC3339 times, or numbers of nanoseconds
+	// since the epoch like the start and end times of spans.
+	timestampType = measureType{name: typeTimestamp, parse: parseTimestamp}
+
+	// sizes are compared in bytes. Values are numbers of bytes with an optional unit like `10KB` or `1.5MiB`.
+	bytesType = measureType{name: typeBytes, parse: parseBytes}
+
+	// byteUnits are the multipliers of the units of sizes, in lower case. KB, MB, GB and TB are powers of 1000,
+	// KiB, MiB, GiB and TiB are powers of 1024.
+	byteUnits = map[string]float64{
+		"":    1,
+		"b":   1,
+		"kb":  1e3,
+		"mb":  1e6,
+		"gb":  1e9,
+		"tb":  1e12,
+		"kib": 1 << 10,
+		"mib": 1 << 20,
+		"gib": 1 << 30,
+		"tib": 1 << 40,
+	}
+)
+
+// MeasureRuleEvaluator evaluates rules on durations, timestamps and sizes. It supports the same operators as
+// FloatRuleEvaluator, and the collection operators.
+type MeasureRuleEvaluator struct {
+	functionFactory *functions.FunctionFactory
+	attrStoreKey    *cache.AttribStoreKey
+	measureType     measureType
+}
+
+func (re *MeasureRuleEvaluator) init() LeafRuleEvaluator {
+	return re
+}
+
+func NewDurationRuleEvaluator(functionFactory *functions.FunctionFactory) LeafRuleEvaluator {
+	return (&MeasureRuleEvaluator{functionFactory: functionFactory, measureType: durationType}).init()
+}
+
+func NewTimestampRuleEvaluator(functionFactory *functions.FunctionFactory) LeafRuleEvaluator {
+	return (&MeasureRuleEvaluator{functionFactory: functionFactory, measureType: timestampType}).init()
+}
+
+func NewBytesRuleEvaluator(functionFactory *functions.FunctionFactory) LeafRuleEvaluator {
+	return (&MeasureRuleEvaluator{functionFactory: functionFactory, measureType: bytesType}).init()
+}
+
+func (re *MeasureRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]interface{}) (bool, error) {
+	compiled, err := re.compileRule(rule, re.functionFactory.CompilePath(*rule.RuleLeaf.ID, re.attrStoreKey))
+	if err != nil {
+		return false, err
+	}
+	return compiled.eval(valueStore)
+}
+
+func (re *MeasureRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
+	re.attrStoreKey = attrStoreKey
+}
+
+func (re *MeasureRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	operator := string(*rule.Operator)
+	if IsCollectionOperator(operator) {
+		return compileCollectionRule(rule, path, re.measureType.elements())
+	}
+
+	values, err := re.measureType.compileValues(operator, string(*rule.Value))
+	if err != nil {
+		return nil, err
+	}
+	return compiledMeasureRule{path: path, operator: operator, measureType: re.measureType, values: values}, nil
+}
+
+// compileValues parses the rule value: a single value for comparisons, a `low,high` pair for between and a list for in
+func (mt measureType) compileValues(operator string, value string) ([]int64, error) {
+	switch operator {
+	case operatorExists, operatorNotExists:
+		return nil, nil
+	case operatorLessThan, operatorLessThanEqual, operatorGreaterThan, operatorGreaterThanEqual, operatorEqual, operatorNotEqual:
+		quantity, err := mt.parse(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("error converting rule value %s to %s: %v", value, mt.name, err)
+		}
+		return []int64{quantity}, nil
+	case operatorBetween, operatorNotBetween, operatorIn, operatorNotIn:
+		parts := strings.Split(value, ",")
+		if IsRangeOperator(operator) && len(parts) != 2 {
+			return nil, fmt.Errorf("invalid number of values for operator %s: %s", operator, value)
+		}
+		values := make([]int64, 0, len(parts))
+		for _, part := range parts {
+			quantity, err := mt.parse(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("error converting rule value %s to %s: %v", part, mt.name, err)
+			}
+			values = append(values, quantity)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s: invalid operator: %s", mt.name, operator)
+}
+
+// fromStore converts a value from the value store. Numbers are taken to be in the base unit of the type.
+func (mt measureType) fromStore(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("valueStore value %d is out of range for %s", v, mt.name)
+		}
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case float64:
+		return int64(math.Round(v)), nil
+	case float32:
+		return int64(math.Round(float64(v))), nil
+	case time.Duration:
+		return int64(v), nil
+	case time.Time:
+		return v.UnixNano(), nil
+	}
+
+	strValue := fmt.Sprintf("%v", value)
+	quantity, err := mt.parse(strValue)
+	if err != nil {
+		return 0, fmt.Errorf("error converting valueStore value %s to %s: %v", strValue, mt.name, err)
+	}
+	return quantity, nil
+}
+
+type compiledMeasureRule struct {
+	path        functions.CompiledPath
+	operator    string
+	measureType measureType
+
+	// values holds the value for comparisons, the range for `between` and the members for `in`
+	values []int64
+}
+
+func (cr compiledMeasureRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled %s eval: Recovered from panic: %v", cr.measureType.name, r)
+		}
+	}()
+
+	valueInterface, ok := cr.path.Evaluate(valueStore)
+
+	switch cr.operator {
+	case operatorExists:
+		return ok && valueInterface != nil, nil
+	case operatorNotExists:
+		return !ok || valueInterface == nil, nil
+	}
+
+	var valueFromStore int64
+	var err error
+	if !ok || valueInterface == nil {
+		err = fmt.Errorf("value not found for id %s", cr.path)
+	} else {
+		valueFromStore, err = cr.measureType.fromStore(valueInterface)
+	}
+
+	if err != nil {
+		switch cr.operator {
+		case operatorIn:
+			logger.Error(LoggerTag, "%v", err)
+			return false, nil
+		case operatorNotIn:
+			logger.Error(LoggerTag, "%v", err)
+			return true, nil
+		}
+		return false, err
+	}
+
+	switch cr.operator {
+	case operatorLessThan:
+		return valueFromStore < cr.values[0], nil
+	case operatorLessThanEqual:
+		return valueFromStore <= cr.values[0], nil
+	case operatorGreaterThan:
+		return valueFromStore > cr.values[0], nil
+	case operatorGreaterThanEqual:
+		return valueFromStore >= cr.values[0], nil
+	case operatorEqual:
+		return valueFromStore == cr.values[0], nil
+	case operatorNotEqual:
+		return valueFromStore != cr.values[0], nil
+	case operatorBetween:
+		return valueFromStore >= cr.values[0] && valueFromStore <= cr.values[1], nil
+	case operatorNotBetween:
+		return !(valueFromStore >= cr.values[0] && valueFromStore <= cr.values[1]), nil
+	case operatorIn:
+		return containsInt64(cr.values, valueFromStore), nil
+	case operatorNotIn:
+		return !containsInt64(cr.values, valueFromStore), nil
+	}
+
+	return false, fmt.Errorf("%s: invalid operator: %s", cr.measureType.name, cr.operator)
+}
+
+func parseDuration(value string) (int64, error) {
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return nanos, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int64(duration), nil
+}
+
+func parseTimestamp(value string) (int64, error) {
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return nanos, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, err
+	}
+	return timestamp.UnixNano(), nil
+}
+
+func parseBytes(value string) (int64, error) {
+	index := len(value)
+	for index > 0 && (value[index-1] < '0' || value[index-1] > '9') && value[index-1] != '.' {
+		index--
+	}
+	number, unit := strings.TrimSpace(value[:index]), strings.ToLower(strings.TrimSpace(value[index:]))
+
+	multiplier, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", value[index:])
+	}
+	if size, err := strconv.ParseInt(number, 10, 64); err == nil && multiplier == 1 {
+		return size, nil
+	}
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+	size *= multiplier
+	if size > math.MaxInt64 || size < math.MinInt64 {
+		return 0, fmt.Errorf("size %s is out of range", value)
+	}
+	return int64(math.Round(size)), nil
+}
+
+func containsInt64(values []int64, value int64) bool {
+	for _, number := range values {
+		if number == value {
+			return true
+		}
+	}
+	return false
+}
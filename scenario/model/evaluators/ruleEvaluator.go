@@ -18,6 +18,9 @@ const (
 	typeFloat              = "float"
 	typeBool               = "bool"
 	typeWorkLoadIdentifier = "workload-identifier"
+	typeDuration           = "duration"
+	typeTimestamp          = "timestamp"
+	typeBytes              = "bytes"
 
 	operatorExists    = "exists"
 	operatorNotExists = "not_exists"
@@ -43,6 +46,72 @@ const (
 	operatorNotBetween       = "not_between"
 )
 
+var (
+	stringOperators = []string{operatorExists, operatorNotExists, operatorMatches, operatorDoesNotMatch, operatorEqual,
+		operatorNotEqual, operatorContains, operatorDoesNotContain, operatorIn, operatorNotIn, operatorBeginsWith,
+		operatorDoesNotBeginWith, operatorEndsWith, operatorDoesNotEndWith}
+
+	numericOperators = []string{operatorExists, operatorNotExists, operatorEqual, operatorNotEqual, operatorLessThan,
+		operatorLessThanEqual, operatorGreaterThan, operatorGreaterThanEqual, operatorBetween, operatorNotBetween,
+		operatorIn, operatorNotIn}
+
+	// operatorsForDatatype lists the operators understood by the LeafRuleEvaluator registered for each datatype. Every
+	// datatype also supports the collectionOperators.
+	operatorsForDatatype = map[string][]string{
+		typeString:             withCollectionOperators(stringOperators),
+		typeKeyValue:           withCollectionOperators(stringOperators),
+		typeWorkLoadIdentifier: withCollectionOperators(workloadIdentifierOperators),
+		typeInteger:            withCollectionOperators(numericOperators),
+		typeFloat:              withCollectionOperators(numericOperators),
+		typeBool:               withCollectionOperators([]string{operatorExists, operatorNotExists, operatorEqual, operatorNotEqual}),
+		typeDuration:           withCollectionOperators(numericOperators),
+		typeTimestamp:          withCollectionOperators(numericOperators),
+		typeBytes:              withCollectionOperators(numericOperators),
+		typeIP:                 withCollectionOperators(ipOperators),
+	}
+)
+
+func withCollectionOperators(operators []string) []string {
+	return append(append([]string{}, operators...), collectionOperators...)
+}
+
+// SupportedOperators returns the operators that a rule of the datatype can use. ok is false if there is no evaluator
+// for the datatype.
+func SupportedOperators(datatype string) (operators []string, ok bool) {
+	operators, ok = operatorsForDatatype[datatype]
+	return operators, ok
+}
+
+// SupportsMatchOptions returns true for the datatypes whose rules can have model.MatchOptions
+func SupportsMatchOptions(datatype string) bool {
+	return datatype == typeString || datatype == typeKeyValue
+}
+
+// ValidateRuleValue returns an error if the value of a rule cannot be parsed by the evaluator of its datatype for the
+// operator, like a `between` rule without two numbers or a `matches` rule with an invalid regex
+func ValidateRuleValue(datatype string, operator string, value string) error {
+	ruleEvaluator := NewRuleEvaluator(nil, nil)
+	leafEvaluator, ok := ruleEvaluator.leafRuleEvaluators[datatype]
+	if !ok {
+		return fmt.Errorf("LeafRuleEvaluator not found for type: %s", datatype)
+	}
+
+	dataType, operatorType, valueType := model.DataType(datatype), model.OperatorTypes(operator), model.ValueTypes(value)
+	rule := model.Rule{Type: model.RULE, RuleLeaf: &model.RuleLeaf{Datatype: &dataType, Operator: &operatorType, Value: &valueType}}
+	_, err := leafEvaluator.compileRule(rule, functions.CompiledPath{})
+	return err
+}
+
+// IsRangeOperator returns true for operators whose value is a `low,high` pair
+func IsRangeOperator(operator string) bool {
+	return operator == operatorBetween || operator == operatorNotBetween
+}
+
+// IsRegexOperator returns true for operators whose value is a regular expression
+func IsRegexOperator(operator string) bool {
+	return operator == operatorMatches || operator == operatorDoesNotMatch
+}
+
 type DataStore map[string]string
 
 func (ds DataStore) String() string {
@@ -58,6 +127,17 @@ type LeafRuleEvaluator interface {
 	init() LeafRuleEvaluator
 	setAttrStoreKey(attrStoreKey *cache.AttribStoreKey)
 	evalRule(rule model.Rule, valueStore map[string]interface{}) (bool, error)
+	compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error)
+}
+
+// compileAndEvalRule compiles and evaluates a rule, for the evaluators which evaluate rules without compiling them
+// but support some operators and options only in compiled rules
+func compileAndEvalRule(leafEvaluator LeafRuleEvaluator, rule model.Rule, path functions.CompiledPath, valueStore map[string]interface{}) (bool, error) {
+	compiled, err := leafEvaluator.compileRule(rule, path)
+	if err != nil {
+		return false, err
+	}
+	return compiled.eval(valueStore)
 }
 
 type GroupRuleEvaluator interface {
@@ -90,6 +170,12 @@ func (re *RuleEvaluator) init() *RuleEvaluator {
 	re.leafRuleEvaluators[typeInteger] = NewFloatRuleEvaluator(re.functionFactory)
 	re.leafRuleEvaluators[typeFloat] = NewFloatRuleEvaluator(re.functionFactory)
 	re.leafRuleEvaluators[typeBool] = NewBooleanEvaluator(re.functionFactory)
+	re.leafRuleEvaluators[typeDuration] = NewDurationRuleEvaluator(re.functionFactory)
+	re.leafRuleEvaluators[typeTimestamp] = NewTimestampRuleEvaluator(re.functionFactory)
+	re.leafRuleEvaluators[typeBytes] = NewBytesRuleEvaluator(re.functionFactory)
+	re.leafRuleEvaluators[typeKeyValue] = NewKeyValueRuleEvaluator(re.functionFactory)
+	re.leafRuleEvaluators[typeWorkLoadIdentifier] = NewWorkloadIdentifierRuleEvaluator(re.functionFactory, re.podDetailsStore)
+	re.leafRuleEvaluators[typeIP] = NewIPRuleEvaluator(re.functionFactory)
 
 	return re
 }
@@ -14,34 +14,7 @@ func (re *RuleGroupEvaluator) init() GroupRuleEvaluator {
 }
 
 func (re *RuleGroupEvaluator) evalRule(rule model.Rule, attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) (bool, error) {
-
-	// evaluate all the rules
-	condition := *rule.Condition
-	result := true // default is true for both `AND` and `OR` to work
-	if condition == model.AND {
-		result = true
-	} else {
-		result = false
-	}
-	for _, childRule := range rule.Rules {
-		ok, err := re.baseRuleEvaluator.evalRule(childRule, attrStoreKey, valueStore)
-		if err != nil {
-			return false, err
-		}
-
-		if condition == model.AND {
-			result = result && ok
-			if !result {
-				break
-			}
-		}
-
-		if condition == model.OR {
-			result = result || ok
-			if result {
-				break
-			}
-		}
-	}
-	return result, nil
+	return rule.Condition.Evaluate(len(rule.Rules), func(index int) (bool, error) {
+		return re.baseRuleEvaluator.evalRule(rule.Rules[index], attrStoreKey, valueStore)
+	})
 }
@@ -0,0 +1,128 @@
+package evaluators
+
+import (
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"strings"
+)
+
+// RuleTrace records how one node of a rule tree was evaluated. Group nodes carry the traces of their child rules in
+// Rules. Leaf nodes carry the attribute path the rule id resolved to and the value extracted from the value store.
+type RuleTrace struct {
+	Type      string `json:"type"`
+	Condition string `json:"condition,omitempty"`
+
+	ID           string      `json:"id,omitempty"`
+	ResolvedPath string      `json:"resolved_path,omitempty"`
+	Value        interface{} `json:"value,omitempty"`
+	ValueFound   bool        `json:"value_found"`
+	Datatype     string      `json:"datatype,omitempty"`
+	Operator     string      `json:"operator,omitempty"`
+	RuleValue    string      `json:"rule_value,omitempty"`
+
+	Result bool         `json:"result"`
+	Error  string       `json:"error,omitempty"`
+	Rules  []*RuleTrace `json:"rules,omitempty"`
+
+	err error
+}
+
+// EvalRuleWithTrace evaluates the rule like EvalRule and also returns a trace of the evaluation of every node in the
+// rule tree. Unlike EvalRule, all the child rules of a group are evaluated so that the trace is complete, but the
+// returned result and error are the same as EvalRule's.
+func (re *RuleEvaluator) EvalRuleWithTrace(rule model.Rule, attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) (bool, *RuleTrace, error) {
+
+	for _, leafEvaluator := range re.leafRuleEvaluators {
+		leafEvaluator.setAttrStoreKey(&attrStoreKey)
+	}
+
+	trace := re.traceRule(rule, &attrStoreKey, valueStore)
+	return trace.Result, trace, trace.err
+}
+
+func (re *RuleEvaluator) traceRule(rule model.Rule, attrStoreKey *cache.AttribStoreKey, valueStore map[string]interface{}) *RuleTrace {
+
+	trace := &RuleTrace{Type: rule.Type}
+
+	if rule.Type == model.RULE_GROUP {
+		if rule.RuleGroup == nil || rule.Condition == nil {
+			return trace.setResult(false, fmt.Errorf("condition is nil"))
+		}
+		trace.Condition = string(*rule.Condition)
+
+		trace.Rules = make([]*RuleTrace, 0, len(rule.Rules))
+		for _, childRule := range rule.Rules {
+			trace.Rules = append(trace.Rules, re.traceRule(childRule, attrStoreKey, valueStore))
+		}
+
+		return trace.setResult(rule.Condition.Evaluate(len(trace.Rules), func(index int) (bool, error) {
+			return trace.Rules[index].Result, trace.Rules[index].err
+		}))
+	}
+
+	if rule.RuleLeaf == nil {
+		return trace.setResult(false, fmt.Errorf("rule is nil"))
+	}
+	if err := re.validate(rule); err != nil {
+		return trace.setResult(false, err)
+	}
+
+	trace.ID = *rule.RuleLeaf.ID
+	trace.Datatype = string(*rule.RuleLeaf.Datatype)
+	trace.Operator = string(*rule.Operator)
+	trace.RuleValue = string(*rule.Value)
+	trace.ResolvedPath = re.functionFactory.ResolvePath(trace.ID, attrStoreKey)
+
+	// the rule is checked on the traced value, so that the path is evaluated once
+	var path functions.CompiledPath
+	path, trace.Value, trace.ValueFound = re.functionFactory.CompilePath(trace.ID, attrStoreKey).EvaluateOnce(valueStore)
+	if !trace.ValueFound {
+		trace.Value = nil
+	}
+
+	ruleEvaluator := re.leafRuleEvaluators[trace.Datatype]
+	if ruleEvaluator == nil {
+		return trace.setResult(false, fmt.Errorf("LeafRuleEvaluator not found for type: %s", trace.Datatype))
+	}
+	return trace.setResult(compileAndEvalRule(ruleEvaluator, rule, path, valueStore))
+}
+
+func (trace *RuleTrace) setResult(result bool, err error) *RuleTrace {
+	trace.Result = result
+	trace.err = err
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	return trace
+}
+
+// String prints the trace as an indented tree with one node per line
+func (trace *RuleTrace) String() string {
+	builder := &strings.Builder{}
+	trace.print(builder, 0)
+	return builder.String()
+}
+
+func (trace *RuleTrace) print(builder *strings.Builder, depth int) {
+	builder.WriteString(strings.Repeat("  ", depth))
+	if trace.Type == model.RULE_GROUP {
+		builder.WriteString(trace.Condition)
+	} else {
+		value := "<not found>"
+		if trace.ValueFound {
+			value = fmt.Sprintf("%#v", trace.Value)
+		}
+		builder.WriteString(fmt.Sprintf("%s [%s] = %s %s %q", trace.ID, trace.ResolvedPath, value, trace.Operator, trace.RuleValue))
+	}
+	builder.WriteString(fmt.Sprintf(" => %v", trace.Result))
+	if trace.Error != "" {
+		builder.WriteString(fmt.Sprintf(" (error: %s)", trace.Error))
+	}
+	builder.WriteString("\n")
+
+	for _, child := range trace.Rules {
+		child.print(builder, depth+1)
+	}
+}
@@ -2,10 +2,12 @@ package evaluators
 
 import (
 	"fmt"
+	"github.com/zerok-ai/zk-utils-go/ds"
 	logger "github.com/zerok-ai/zk-utils-go/logs"
 	"github.com/zerok-ai/zk-utils-go/scenario/model"
 	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
 	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"golang.org/x/text/unicode/norm"
 	"regexp"
 	"strings"
 )
@@ -37,6 +39,10 @@ func (re *StringRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]i
 	operator := string(*rule.Operator)
 	valueFromRule := string(*rule.Value)
 
+	if IsCollectionOperator(operator) || !rule.Options.IsEmpty() {
+		return compileAndEvalRule(re, rule, re.functionFactory.CompilePath(attributeID, re.attrStoreKey), valueStore)
+	}
+
 	valueFromStoreI, ok := re.functionFactory.EvaluateString(attributeID, valueStore, re.attrStoreKey)
 
 	switch operator {
@@ -108,3 +114,220 @@ func (re *StringRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]i
 func (re *StringRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
 	re.attrStoreKey = attrStoreKey
 }
+
+func (re *StringRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	if IsCollectionOperator(string(*rule.Operator)) {
+		return compileCollectionRule(rule, path, stringElementsFor(rule.Options))
+	}
+
+	compiled, err := newCompiledStringRule(path, string(*rule.Operator), string(*rule.Value), rule.Options)
+	if err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+func newCompiledStringRule(path functions.CompiledPath, operator string, value string, options *model.MatchOptions) (compiledStringRule, error) {
+	compiled := compiledStringRule{
+		path:     path,
+		operator: operator,
+	}
+	if options != nil {
+		compiled.options = *options
+	}
+	compiled.value = compiled.normalize(value)
+
+	switch compiled.operator {
+	case operatorExists, operatorNotExists:
+	case operatorEqual, operatorNotEqual, operatorContains, operatorDoesNotContain, operatorBeginsWith,
+		operatorDoesNotBeginWith, operatorEndsWith, operatorDoesNotEndWith:
+		if compiled.options.Glob {
+			compiled.globs = []*regexp.Regexp{compileGlob(compiled.value, compiled.operator)}
+		}
+	case operatorMatches, operatorDoesNotMatch:
+		expression := value
+		if compiled.options.IgnoreCase {
+			expression = "(?i)" + expression
+		}
+		regex, err := regexp.Compile(expression)
+		if err != nil {
+			return compiled, fmt.Errorf("string: invalid regex %s: %v", value, err)
+		}
+		compiled.regex = regex
+	case operatorIn, operatorNotIn:
+		values := strings.Split(value, ",")
+		for index := range values {
+			values[index] = compiled.normalize(values[index])
+			if compiled.options.Glob {
+				compiled.globs = append(compiled.globs, compileGlob(values[index], compiled.operator))
+			}
+		}
+		compiled.values = make(ds.Set[string]).AddBulk(values)
+	default:
+		return compiled, fmt.Errorf("string: invalid operator: %s", compiled.operator)
+	}
+	return compiled, nil
+}
+
+type compiledStringRule struct {
+	path     functions.CompiledPath
+	operator string
+	options  model.MatchOptions
+
+	// value is the normalized value of the rule
+	value  string
+	regex  *regexp.Regexp
+	values ds.Set[string]
+
+	// globs are the values of the rule as regexes, with the glob option
+	globs []*regexp.Regexp
+}
+
+func (cr compiledStringRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled string eval: Recovered from panic: %v", r)
+		}
+	}()
+
+	valueFromStoreI, ok := cr.path.Evaluate(valueStore)
+
+	switch cr.operator {
+	case operatorExists:
+		return ok && valueFromStoreI != nil, nil
+	case operatorNotExists:
+		return !ok || valueFromStoreI == nil, nil
+	}
+
+	if !ok {
+		return false, fmt.Errorf("value for attributeName: %s not found in valueStore", cr.path)
+	}
+
+	valueFromStore, isString := valueFromStoreI.(string)
+	if !isString {
+		valueFromStore = fmt.Sprintf("%v", valueFromStoreI)
+	}
+	return cr.match(valueFromStore)
+}
+
+// match applies the operator of the rule to a string from the value store
+func (cr compiledStringRule) match(valueFromStore string) (bool, error) {
+	valueFromStore = cr.normalize(valueFromStore)
+
+	if cr.globs != nil {
+		matched := false
+		for _, glob := range cr.globs {
+			if glob.MatchString(valueFromStore) {
+				matched = true
+				break
+			}
+		}
+		return matched != isNegatedOperator(cr.operator), nil
+	}
+
+	switch cr.operator {
+
+	case operatorMatches:
+		return cr.regex.MatchString(valueFromStore), nil
+	case operatorDoesNotMatch:
+		return !cr.regex.MatchString(valueFromStore), nil
+	case operatorEqual:
+		return valueFromStore == cr.value, nil
+	case operatorNotEqual:
+		return valueFromStore != cr.value, nil
+	case operatorContains:
+		return strings.Contains(valueFromStore, cr.value), nil
+	case operatorDoesNotContain:
+		return !strings.Contains(valueFromStore, cr.value), nil
+	case operatorIn:
+		return cr.values.Contains(valueFromStore), nil
+	case operatorNotIn:
+		return !cr.values.Contains(valueFromStore), nil
+	case operatorBeginsWith:
+		return strings.HasPrefix(valueFromStore, cr.value), nil
+	case operatorDoesNotBeginWith:
+		return !strings.HasPrefix(valueFromStore, cr.value), nil
+	case operatorEndsWith:
+		return strings.HasSuffix(valueFromStore, cr.value), nil
+	case operatorDoesNotEndWith:
+		return !strings.HasSuffix(valueFromStore, cr.value), nil
+	}
+
+	return false, fmt.Errorf("string: invalid operator: %s", cr.operator)
+}
+
+// normalize applies the trim, normalize and ignore_case options to a string
+func (cr compiledStringRule) normalize(value string) string {
+	return normalizeString(value, cr.options)
+}
+
+func normalizeString(value string, options model.MatchOptions) string {
+	if options.Trim {
+		value = strings.TrimSpace(value)
+	}
+	if options.Normalize {
+		value = norm.NFC.String(value)
+	}
+	if options.IgnoreCase {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// compileGlob converts a glob to a regex, where `*` matches any characters and `?` matches one character. The regex
+// matches the whole string for equal and in, a prefix for begins_with, a suffix for ends_with and any part for
+// contains.
+func compileGlob(glob string, operator string) *regexp.Regexp {
+	builder := strings.Builder{}
+	switch operator {
+	case operatorEqual, operatorNotEqual, operatorIn, operatorNotIn, operatorBeginsWith, operatorDoesNotBeginWith:
+		builder.WriteString("^")
+	}
+	for _, char := range glob {
+		switch char {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	switch operator {
+	case operatorEqual, operatorNotEqual, operatorIn, operatorNotIn, operatorEndsWith, operatorDoesNotEndWith:
+		builder.WriteString("$")
+	}
+	return regexp.MustCompile("(?s)" + builder.String())
+}
+
+// stringElementsFor returns the elementType of strings compared with the match options
+func stringElementsFor(options *model.MatchOptions) elementType {
+	if options.IsEmpty() {
+		return stringElements
+	}
+	matchOptions := *options
+	return elementType{
+		name: typeString,
+		parseValue: func(value string) (interface{}, error) {
+			value = normalizeString(value, matchOptions)
+			if matchOptions.Glob {
+				return compileGlob(value, operatorEqual), nil
+			}
+			return value, nil
+		},
+		parseElement: func(element interface{}) (interface{}, error) {
+			value, err := stringElements.parseElement(element)
+			if err != nil {
+				return nil, err
+			}
+			return normalizeString(value.(string), matchOptions), nil
+		},
+		equal: func(element interface{}, value interface{}) bool {
+			if glob, ok := value.(*regexp.Regexp); ok {
+				return glob.MatchString(element.(string))
+			}
+			return element == value
+		},
+	}
+}
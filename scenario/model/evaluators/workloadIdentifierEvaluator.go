@@ -0,0 +1,219 @@
+package evaluators
+
+import (
+	"fmt"
+	logger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/podDetails"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const workloadWildcard = "*"
+
+var workloadIdentifierOperators = []string{operatorExists, operatorNotExists, operatorEqual, operatorNotEqual,
+	operatorIn, operatorNotIn, operatorMatches, operatorDoesNotMatch, operatorInCidr, operatorNotInCidr}
+
+// WorkloadIdentifierRuleEvaluator evaluates rules on the ip of a pod, like the destination ip of a request. The ip
+// is resolved through the pod details store to the `namespace/service` of the pod, in the format of
+// model.Workload.Service, which is what the rule is compared with.
+//
+// equal, not_equal, in and not_in compare with `namespace/service` values, where either part can be `*` and a value
+// without a namespace matches in any namespace. matches and does_not_match take a regex for `namespace/service`.
+// exists and not_exists check if the ip belongs to a known pod. in_cidr and not_in_cidr compare the ip itself, without
+// resolving it, with a comma separated list of ranges like the ip datatype does. The collection operators resolve each
+// ip of a list and compare the workloads with the `namespace/service` values like equal does.
+type WorkloadIdentifierRuleEvaluator struct {
+	functionFactory *functions.FunctionFactory
+	podDetailsStore *stores.LocalCacheHSetStore
+	attrStoreKey    *cache.AttribStoreKey
+}
+
+func (re *WorkloadIdentifierRuleEvaluator) init() LeafRuleEvaluator {
+	return re
+}
+
+func NewWorkloadIdentifierRuleEvaluator(functionFactory *functions.FunctionFactory, podDetailsStore *stores.LocalCacheHSetStore) LeafRuleEvaluator {
+	return (&WorkloadIdentifierRuleEvaluator{functionFactory: functionFactory, podDetailsStore: podDetailsStore}).init()
+}
+
+func (re *WorkloadIdentifierRuleEvaluator) evalRule(rule model.Rule, valueStore map[string]interface{}) (bool, error) {
+	compiled, err := re.compileRule(rule, re.functionFactory.CompilePath(*rule.RuleLeaf.ID, re.attrStoreKey))
+	if err != nil {
+		return false, err
+	}
+	return compiled.eval(valueStore)
+}
+
+func (re *WorkloadIdentifierRuleEvaluator) setAttrStoreKey(attrStoreKey *cache.AttribStoreKey) {
+	re.attrStoreKey = attrStoreKey
+}
+
+func (re *WorkloadIdentifierRuleEvaluator) compileRule(rule model.Rule, path functions.CompiledPath) (compiledLeafRule, error) {
+	compiled := compiledWorkloadIdentifierRule{
+		path:            path,
+		operator:        string(*rule.Operator),
+		podDetailsStore: re.podDetailsStore,
+	}
+
+	value := string(*rule.Value)
+	if IsCollectionOperator(compiled.operator) {
+		return compileCollectionRule(rule, path, workloadElements(re.podDetailsStore))
+	}
+
+	switch compiled.operator {
+	case operatorExists, operatorNotExists:
+	case operatorEqual, operatorNotEqual:
+		compiled.workloads = []workloadPattern{newWorkloadPattern(value)}
+	case operatorIn, operatorNotIn:
+		for _, part := range strings.Split(value, ",") {
+			compiled.workloads = append(compiled.workloads, newWorkloadPattern(part))
+		}
+	case operatorInCidr, operatorNotInCidr:
+		ranges, err := parseIPRanges(value)
+		if err != nil {
+			return nil, fmt.Errorf("workload-identifier: %v", err)
+		}
+		compiled.ranges = ranges
+	case operatorMatches, operatorDoesNotMatch:
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("workload-identifier: invalid regex %s: %v", value, err)
+		}
+		compiled.regex = regex
+	default:
+		return nil, fmt.Errorf("workload-identifier: invalid operator: %s", compiled.operator)
+	}
+	return compiled, nil
+}
+
+// workloadPattern is a `namespace/service` value of a rule, either part of which can be a wildcard
+type workloadPattern struct {
+	namespace string
+	service   string
+}
+
+func newWorkloadPattern(value string) workloadPattern {
+	value = strings.TrimSpace(value)
+	namespace, service, found := strings.Cut(value, "/")
+	if !found {
+		return workloadPattern{namespace: workloadWildcard, service: value}
+	}
+	return workloadPattern{namespace: namespace, service: service}
+}
+
+func (pattern workloadPattern) matches(namespace string, service string) bool {
+	return (pattern.namespace == workloadWildcard || pattern.namespace == namespace) &&
+		(pattern.service == workloadWildcard || pattern.service == service)
+}
+
+// workloadElements returns the elementType of lists of ips, which are resolved to workloads. An ip which does not
+// belong to a known pod matches no value.
+func workloadElements(podDetailsStore *stores.LocalCacheHSetStore) elementType {
+	return elementType{
+		name: typeWorkLoadIdentifier,
+		parseValue: func(value string) (interface{}, error) {
+			return newWorkloadPattern(value), nil
+		},
+		parseElement: func(element interface{}) (interface{}, error) {
+			namespace, service, found := resolveWorkload(fmt.Sprintf("%v", element), podDetailsStore)
+			if !found {
+				return nil, fmt.Errorf("no pod found for ip %v", element)
+			}
+			return workloadPattern{namespace: namespace, service: service}, nil
+		},
+		equal: func(element interface{}, value interface{}) bool {
+			workload := element.(workloadPattern)
+			return value.(workloadPattern).matches(workload.namespace, workload.service)
+		},
+	}
+}
+
+type compiledWorkloadIdentifierRule struct {
+	path            functions.CompiledPath
+	operator        string
+	podDetailsStore *stores.LocalCacheHSetStore
+	workloads       []workloadPattern
+	regex           *regexp.Regexp
+	ranges          ipRanges
+}
+
+func (cr compiledWorkloadIdentifierRule) eval(valueStore map[string]interface{}) (bool, error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorF(LoggerTag, "In compiled workload-identifier eval: Recovered from panic: %v", r)
+		}
+	}()
+
+	valueFromStoreI, ok := cr.path.Evaluate(valueStore)
+	if !ok || valueFromStoreI == nil {
+		switch cr.operator {
+		case operatorExists:
+			return false, nil
+		case operatorNotExists:
+			return true, nil
+		}
+		return false, fmt.Errorf("value for attributeName: %s not found in valueStore", cr.path)
+	}
+
+	if cr.operator == operatorInCidr || cr.operator == operatorNotInCidr {
+		addr, err := parseIP(fmt.Sprintf("%v", valueFromStoreI))
+		if err != nil {
+			return false, fmt.Errorf("workload-identifier: %s: %v", cr.path, err)
+		}
+		return cr.ranges.contains(addr) == (cr.operator == operatorInCidr), nil
+	}
+
+	namespace, service, found := resolveWorkload(fmt.Sprintf("%v", valueFromStoreI), cr.podDetailsStore)
+	switch cr.operator {
+	case operatorExists:
+		return found, nil
+	case operatorNotExists:
+		return !found, nil
+	case operatorMatches:
+		return found && cr.regex.MatchString(namespace+"/"+service), nil
+	case operatorDoesNotMatch:
+		return !found || !cr.regex.MatchString(namespace+"/"+service), nil
+	}
+
+	matched := false
+	for _, workload := range cr.workloads {
+		if found && workload.matches(namespace, service) {
+			matched = true
+			break
+		}
+	}
+	if isNegatedOperator(cr.operator) {
+		return !matched, nil
+	}
+	return matched, nil
+}
+
+// resolveWorkload returns the namespace and service of the pod with the ip. The port, if any, is ignored. The
+// service is the service name of the pod, or the name of its workload if it has none.
+func resolveWorkload(ip string, podDetailsStore *stores.LocalCacheHSetStore) (string, string, bool) {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if podDetailsStore == nil || *podDetailsStore == nil {
+		return "", "", false
+	}
+
+	details := podDetails.GetPodDetailsFromPodDetailsStore(ip, podDetailsStore)
+	service := details.Metadata.ServiceName
+	if service == "" {
+		service = details.Telemetry.ServiceName
+	}
+	if service == "" {
+		service = details.Metadata.WorkloadName
+	}
+	if service == "" {
+		return "", "", false
+	}
+	return details.Metadata.Namespace, service, true
+}
@@ -1,16 +1,31 @@
 package model
 
 import (
+	"encoding/json"
 	"github.com/zerok-ai/zk-utils-go/interfaces"
 	"sort"
+	"time"
 )
 
 const (
 	FILTER   = "filter"
 	WORKLOAD = "workload"
 
-	CONDITION_AND = "AND"
-	CONDITION_OR  = "OR"
+	// SEQUENCE, COUNT and DESCENDANT filters are evaluated on the spans of a trace. They ignore the condition.
+	//
+	// SEQUENCE: spans of the workloads appear in the order of workload_ids, each starting after the previous one
+	// ends, with a gap of at most `within` when it is set.
+	// COUNT: spans of the workloads appear at least min_count times, all starting within `within` when it is set.
+	// DESCENDANT: a span of each workload is a descendant of a span of the previous workload in workload_ids.
+	SEQUENCE   = "sequence"
+	COUNT      = "count"
+	DESCENDANT = "descendant"
+
+	CONDITION_AND  = "AND"
+	CONDITION_OR   = "OR"
+	CONDITION_NOT  = "NOT"
+	CONDITION_NAND = "NAND"
+	CONDITION_NOR  = "NOR"
 )
 
 type Filter struct {
@@ -18,6 +33,42 @@ type Filter struct {
 	Condition   Condition    `json:"condition"`
 	Filters     *Filters     `json:"filters,omitempty"`
 	WorkloadIds *WorkloadIds `json:"workload_ids,omitempty"`
+	Within      *string      `json:"within,omitempty"`
+	MinCount    *int         `json:"min_count,omitempty"`
+}
+
+// IsSpanFilter returns true for the filter types which are evaluated on the spans of a trace
+func (f Filter) IsSpanFilter() bool {
+	return f.Type == SEQUENCE || f.Type == COUNT || f.Type == DESCENDANT
+}
+
+// MarshalJSON leaves out the condition of span filters when it is empty, as they ignore it and a strict decoder
+// accepts only the known conditions
+func (f Filter) MarshalJSON() ([]byte, error) {
+	type filter Filter
+	if !f.IsSpanFilter() || f.Condition != "" {
+		return json.Marshal(filter(f))
+	}
+	return json.Marshal(struct {
+		filter
+		Condition Condition `json:"condition,omitempty"`
+	}{filter: filter(f)})
+}
+
+// GetWithin returns the parsed `within` duration of the filter, or 0 if it is not set
+func (f Filter) GetWithin() (time.Duration, error) {
+	if f.Within == nil {
+		return 0, nil
+	}
+	return time.ParseDuration(*f.Within)
+}
+
+// GetMinCount returns the min_count of the filter, 1 if it is not set
+func (f Filter) GetMinCount() int {
+	if f.MinCount == nil {
+		return 1
+	}
+	return *f.MinCount
 }
 
 func (f Filter) Equals(otherInterface interfaces.ZKComparable) bool {
@@ -40,8 +91,18 @@ func (f Filter) Equals(otherInterface interfaces.ZKComparable) bool {
 		return false
 	}
 
-	// match workloads
-	if f.WorkloadIds != nil && !(*f.WorkloadIds).Equals(*other.WorkloadIds) {
+	// match workloads. The order of the workloads matters for sequence and descendant filters.
+	if f.WorkloadIds != nil {
+		if f.Type == SEQUENCE || f.Type == DESCENDANT {
+			if !(*f.WorkloadIds).equalsInOrder(*other.WorkloadIds) {
+				return false
+			}
+		} else if !(*f.WorkloadIds).Equals(*other.WorkloadIds) {
+			return false
+		}
+	}
+
+	if !equalsPointer(f.Within, other.Within) || !equalsPointer(f.MinCount, other.MinCount) {
 		return false
 	}
 
@@ -75,6 +136,18 @@ func (f Filter) LessThan(other Filter) bool {
 				return false
 			}
 			return (*f.Filters).LessThan(*other.Filters)
+		} else if f.IsSpanFilter() {
+			// the order of the workloads matters for span filters, so they are compared in order
+			if f.WorkloadIds == nil || other.WorkloadIds == nil {
+				return f.WorkloadIds == nil && other.WorkloadIds != nil
+			}
+			if !(*f.WorkloadIds).equalsInOrder(*other.WorkloadIds) {
+				return (*f.WorkloadIds).lessThanInOrder(*other.WorkloadIds)
+			}
+			if !equalsPointer(f.Within, other.Within) {
+				return lessThanPointer(f.Within, other.Within)
+			}
+			return lessThanPointer(f.MinCount, other.MinCount)
 		}
 	}
 
@@ -82,7 +155,7 @@ func (f Filter) LessThan(other Filter) bool {
 }
 
 func (f Filter) sort() {
-	if f.Type == WORKLOAD {
+	if f.Type == WORKLOAD || (f.Type == COUNT && f.WorkloadIds != nil) {
 		sort.Strings(*f.WorkloadIds)
 	} else if f.Type == FILTER {
 		(*f.Filters).sort()
@@ -155,6 +228,19 @@ func (s WorkloadIds) Equals(other WorkloadIds) bool {
 	}
 	return true
 }
+
+func (s WorkloadIds) equalsInOrder(other WorkloadIds) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for i := range s {
+		if s[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (s WorkloadIds) LessThan(other WorkloadIds) bool {
 
 	// sort and check equality
@@ -169,3 +255,27 @@ func (s WorkloadIds) LessThan(other WorkloadIds) bool {
 
 	return len(s) < len(other)
 }
+
+func (s WorkloadIds) lessThanInOrder(other WorkloadIds) bool {
+	for i := 0; i < len(s) && i < len(other); i++ {
+		if s[i] != other[i] {
+			return s[i] < other[i]
+		}
+	}
+	return len(s) < len(other)
+}
+
+// lessThanPointer orders nil before any value
+func lessThanPointer[T int | string](value *T, other *T) bool {
+	if value == nil || other == nil {
+		return value == nil && other != nil
+	}
+	return *value < *other
+}
+
+func equalsPointer[T comparable](value *T, other *T) bool {
+	if value == nil || other == nil {
+		return value == nil && other == nil
+	}
+	return *value == *other
+}
@@ -108,7 +108,7 @@ func (g GroupBy) Equals(other GroupBy) bool {
 }
 
 func (r RateLimit) Equals(o RateLimit) bool {
-	if r.BucketMaxSize == o.BucketMaxSize && r.BucketRefillSize == o.BucketRefillSize && r.TickDuration == o.TickDuration {
+	if r.BucketMaxSize == o.BucketMaxSize && r.BucketRefillSize == o.BucketRefillSize && r.TickDuration == o.TickDuration && r.Scope == o.Scope {
 		return true
 	}
 
@@ -204,7 +204,7 @@ func (r RuleGroup) Equals(other RuleGroup) bool {
 		return false
 	}
 
-	if *r.Condition != *other.Condition {
+	if r.Condition != nil && *r.Condition != *other.Condition {
 		return false
 	}
 
@@ -225,7 +225,8 @@ func (r RuleGroup) LessThan(other RuleGroup) bool {
 		return false
 	}
 
-	if *r.Condition < *other.Condition {
+	// a greater condition falls through to the rules, as the order decides the WorkLoadUUID of stored workloads
+	if r.Condition != nil && other.Condition != nil && *r.Condition < *other.Condition {
 		return true
 	}
 
@@ -268,13 +269,91 @@ type RuleLeaf struct {
 	Operator *OperatorTypes `json:"operator,omitempty"`
 	Value    *ValueTypes    `json:"value,omitempty"`
 	JsonPath *[]string      `json:"json_path,omitempty"`
+	Options  *MatchOptions  `json:"options,omitempty"`
 }
 
 func (r RuleLeaf) String() string {
-	return fmt.Sprintf("RuleLeaf{ID: %v, Field: %v, Datatype: %v, Input: %v, Operator: %v, Value: %v, JsonPath: %v}", *r.ID, *r.Field, *r.Datatype, *r.Input, *r.Operator, *r.Value, *r.JsonPath)
+	return fmt.Sprintf("RuleLeaf{ID: %v, Field: %v, Datatype: %v, Input: %v, Operator: %v, Value: %v, JsonPath: %v, Options: %v}", *r.ID, *r.Field, *r.Datatype, *r.Input, *r.Operator, *r.Value, *r.JsonPath, r.Options)
+}
+
+const (
+	MatchOptionIgnoreCase = "ignore_case"
+	MatchOptionNormalize  = "normalize"
+	MatchOptionTrim       = "trim"
+	MatchOptionGlob       = "glob"
+)
+
+// MatchOptions change how the values of a string or key-value rule are compared, for every operator. They are set on
+// the rule leaf instead of chaining functions like #toLowerCase into the id, so that rules stay comparable.
+type MatchOptions struct {
+	// IgnoreCase compares the values in lower case. For matches and does_not_match, the regex ignores case.
+	IgnoreCase bool `json:"ignore_case,omitempty"`
+
+	// Normalize converts the values to the unicode normalization form NFC
+	Normalize bool `json:"normalize,omitempty"`
+
+	// Trim removes leading and trailing whitespace from the values
+	Trim bool `json:"trim,omitempty"`
+
+	// Glob makes `*` in the values of the rule match any characters, like `*.example.com`, and `?` match one
+	// character. It does not apply to matches and does_not_match.
+	Glob bool `json:"glob,omitempty"`
+}
+
+// Names returns the names of the options which are set
+func (o MatchOptions) Names() []string {
+	names := make([]string, 0)
+	if o.IgnoreCase {
+		names = append(names, MatchOptionIgnoreCase)
+	}
+	if o.Normalize {
+		names = append(names, MatchOptionNormalize)
+	}
+	if o.Trim {
+		names = append(names, MatchOptionTrim)
+	}
+	if o.Glob {
+		names = append(names, MatchOptionGlob)
+	}
+	return names
+}
+
+// Set sets the option with the name
+func (o *MatchOptions) Set(name string) error {
+	switch name {
+	case MatchOptionIgnoreCase:
+		o.IgnoreCase = true
+	case MatchOptionNormalize:
+		o.Normalize = true
+	case MatchOptionTrim:
+		o.Trim = true
+	case MatchOptionGlob:
+		o.Glob = true
+	default:
+		return fmt.Errorf("unknown match option %q", name)
+	}
+	return nil
+}
+
+// IsEmpty returns true if no option is set. Empty options, like `"options": {}`, are the same as no options.
+func (o *MatchOptions) IsEmpty() bool {
+	return o == nil || *o == MatchOptions{}
+}
+
+// orNil returns nil for empty options
+func (o *MatchOptions) orNil() *MatchOptions {
+	if o.IsEmpty() {
+		return nil
+	}
+	return o
+}
+
+func (o MatchOptions) String() string {
+	return "[" + strings.Join(o.Names(), ",") + "]"
 }
 
 func (r RuleLeaf) Equals(other RuleLeaf) bool {
+	r.Options, other.Options = r.Options.orNil(), other.Options.orNil()
 	return reflect.DeepEqual(r, other)
 }
 
@@ -357,6 +436,15 @@ func (r RuleLeaf) LessThan(other RuleLeaf) bool {
 		return false
 	}
 
+	options, otherOptions := r.Options.orNil(), other.Options.orNil()
+	if options == nil && otherOptions != nil {
+		return true
+	} else if options != nil && otherOptions == nil {
+		return false
+	} else if options != nil && otherOptions != nil && *options != *otherOptions {
+		return options.String() < otherOptions.String()
+	}
+
 	return false
 }
 
@@ -474,10 +562,76 @@ type TraceRole string
 const (
 	AND Condition = "AND"
 	OR  Condition = "OR"
+
+	// NOT negates its only rule. NAND is true unless all the rules are true and NOR is true if none of them is.
+	NOT  Condition = "NOT"
+	NAND Condition = "NAND"
+	NOR  Condition = "NOR"
 )
 
 type Condition string
 
+// IsValid returns true for the conditions that Evaluate understands
+func (c Condition) IsValid() bool {
+	switch c {
+	case AND, OR, NOT, NAND, NOR:
+		return true
+	}
+	return false
+}
+
+// Evaluate combines the results of evalChild for count children as per the condition. Evaluation stops at the first
+// child which decides the result. `AND` of no children is true and `OR` of no children is false.
+//
+// An empty condition is evaluated as `AND`. This changes the earlier behaviour, where a rule group with an empty
+// condition was false and a filter with an empty condition was true, whatever their children. Validation rejects
+// empty conditions, so only scenarios which were not validated are affected.
+func (c Condition) Evaluate(count int, evalChild func(index int) (bool, error)) (bool, error) {
+	switch c {
+	case "":
+		return AND.Evaluate(count, evalChild)
+	case NOT:
+		if count != 1 {
+			return false, fmt.Errorf("condition %s needs exactly one child, found %d", c, count)
+		}
+		result, err := evalChild(0)
+		if err != nil {
+			return false, err
+		}
+		return !result, nil
+	case NAND, NOR:
+		positive := AND
+		if c == NOR {
+			positive = OR
+		}
+		result, err := positive.Evaluate(count, evalChild)
+		if err != nil {
+			return false, err
+		}
+		return !result, nil
+	case AND, OR:
+	default:
+		return false, fmt.Errorf("unknown condition: %s", c)
+	}
+
+	// evaluate all the children
+	result := c == AND // default is true for `AND` and false for `OR`
+	for index := 0; index < count; index++ {
+		ok, err := evalChild(index)
+		if err != nil {
+			return false, err
+		}
+
+		if c == AND && !ok {
+			return false, nil
+		}
+		if c == OR && ok {
+			return true, nil
+		}
+	}
+	return result, nil
+}
+
 func WorkLoadUUID(w Workload) uuid.UUID {
 	w.Rule.Rules.Sort()
 	jStr, _ := json.Marshal(w)
@@ -509,9 +663,26 @@ func (g GroupBy) LessThan(other GroupBy) bool {
 }
 
 type RateLimit struct {
-	BucketMaxSize    int    `json:"bucket_max_size"`
-	BucketRefillSize int    `json:"bucket_refill_size"`
-	TickDuration     string `json:"tick_duration"`
+	BucketMaxSize    int            `json:"bucket_max_size"`
+	BucketRefillSize int            `json:"bucket_refill_size"`
+	TickDuration     string         `json:"tick_duration"`
+	Scope            RateLimitScope `json:"scope,omitempty"`
+}
+
+// RateLimitScope tells whether a rate limit applies to each group key of a scenario or to the scenario as a whole
+type RateLimitScope string
+
+const (
+	// RateLimitPerGroup limits every group key of the scenario separately. It is the default.
+	RateLimitPerGroup RateLimitScope = "group"
+
+	// RateLimitPerScenario limits the scenario across all its group keys
+	RateLimitPerScenario RateLimitScope = "scenario"
+)
+
+// IsValid returns true for the known scopes and the empty scope, which is RateLimitPerGroup
+func (s RateLimitScope) IsValid() bool {
+	return s == "" || s == RateLimitPerGroup || s == RateLimitPerScenario
 }
 
 func (r RateLimit) LessThan(other RateLimit) bool {
@@ -526,6 +697,9 @@ func (r RateLimit) LessThan(other RateLimit) bool {
 			if r.TickDuration < other.TickDuration {
 				return true
 			}
+			if r.TickDuration == other.TickDuration && r.Scope < other.Scope {
+				return true
+			}
 		}
 	}
 	return false
@@ -625,6 +799,11 @@ func (in *RuleLeaf) DeepCopyInto(out *RuleLeaf) {
 			copy(*out, *in)
 		}
 	}
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = new(MatchOptions)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleLeaf.
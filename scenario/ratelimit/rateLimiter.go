@@ -0,0 +1,238 @@
+package ratelimit
+
+import (
+	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	zktick "github.com/zerok-ai/zk-utils-go/ticker"
+	"sync"
+	"time"
+)
+
+var LogTag = "scenario_rate_limiter"
+
+// RateLimiter enforces the rate limits of scenarios. Every model.RateLimit of a scenario is a token bucket which
+// starts full with BucketMaxSize tokens and gets BucketRefillSize tokens every TickDuration. A rate limit with the
+// model.RateLimitPerScenario scope has one bucket for the whole scenario, and any other rate limit has a bucket for
+// each group key of the scenario.
+//
+// A rate limiter created with NewRateLimiterForStore runs Sync on a zktick.TickerTask, which reloads the scenarios
+// whose version changed in the store and refills the buckets. Allow also refills the buckets of a scenario with the
+// ticks since the last refill, so that a bucket does not wait for the next run of the task.
+type RateLimiter struct {
+	mutex     sync.Mutex
+	scenarios map[string]*scenarioLimiter
+	now       func() time.Time
+
+	store      ScenarioStore
+	tickerTask *zktick.TickerTask
+}
+
+// ScenarioStore is the source of the scenarios of a rate limiter, typically a redis.VersionedStore of model.Scenario
+type ScenarioStore interface {
+	GetAllValues() map[string]*model.Scenario
+}
+
+// scenarioLimiter holds the buckets of one version of a scenario
+type scenarioLimiter struct {
+	version    string
+	rateLimits []model.RateLimit
+
+	// tickDurations and lastTicks are the tick duration and the time of the last refill of each rate limit
+	tickDurations []time.Duration
+	lastTicks     []time.Time
+
+	// scenarioBuckets are the tokens left in the buckets of the rate limits with the scenario scope. The tokens of
+	// the other rate limits are left out.
+	scenarioBuckets []int
+
+	// buckets are the tokens left in the buckets of the rate limits with the group scope, by group key
+	buckets map[string][]int
+}
+
+// NewRateLimiter creates a rate limiter for the scenarios, typically VersionedStore.GetAllValues(). Refresh has to be
+// called with the latest scenarios when they change.
+func NewRateLimiter(scenarios map[string]*model.Scenario) *RateLimiter {
+	return NewRateLimiterWithClock(scenarios, time.Now)
+}
+
+// NewRateLimiterWithClock creates a rate limiter whose buckets are refilled as per the time returned by now
+func NewRateLimiterWithClock(scenarios map[string]*model.Scenario, now func() time.Time) *RateLimiter {
+	rl := &RateLimiter{scenarios: make(map[string]*scenarioLimiter), now: now}
+	rl.Refresh(scenarios)
+	return rl
+}
+
+// NewRateLimiterForStore creates a rate limiter for the scenarios in the store, and starts a ticker task which calls
+// Sync every syncInterval. Close stops the task.
+func NewRateLimiterForStore(store ScenarioStore, syncInterval time.Duration) *RateLimiter {
+	rl := NewRateLimiter(store.GetAllValues())
+	rl.store = store
+	rl.tickerTask = zktick.GetNewTickerTask(LogTag, syncInterval, rl.Sync).Start()
+	return rl
+}
+
+// Refresh updates the rate limits with the latest scenarios. The buckets of a scenario are reset only when its
+// version changes, so Refresh can be called with VersionedStore.GetAllValues() after every refresh of the store.
+func (rl *RateLimiter) Refresh(scenarios map[string]*model.Scenario) {
+	latestScenarios := make(map[string]*model.Scenario)
+	for _, scenario := range scenarios {
+		if scenario != nil {
+			latestScenarios[scenario.Id] = scenario
+		}
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for scenarioId, limiter := range rl.scenarios {
+		scenario, ok := latestScenarios[scenarioId]
+		if ok && scenario.Version == limiter.version {
+			continue
+		}
+		delete(rl.scenarios, scenarioId)
+	}
+
+	for scenarioId, scenario := range latestScenarios {
+		if _, ok := rl.scenarios[scenarioId]; ok || len(scenario.RateLimit) == 0 {
+			continue
+		}
+		rl.scenarios[scenarioId] = rl.newScenarioLimiter(scenario)
+	}
+}
+
+// Sync reloads the scenarios from the store of a rate limiter created with NewRateLimiterForStore, and refills the
+// buckets of all the scenarios with the ticks since their last refill
+func (rl *RateLimiter) Sync() {
+	if rl.store != nil {
+		rl.Refresh(rl.store.GetAllValues())
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for _, limiter := range rl.scenarios {
+		rl.refillElapsedTicks(limiter)
+	}
+}
+
+// Allow returns true if the scenario has a token left in all its buckets for the group key, and takes a token from
+// each. Scenarios without rate limits are always allowed.
+func (rl *RateLimiter) Allow(scenarioId string, groupKey string) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	limiter, ok := rl.scenarios[scenarioId]
+	if !ok {
+		return true
+	}
+	rl.refillElapsedTicks(limiter)
+
+	buckets, ok := limiter.buckets[groupKey]
+	if !ok {
+		buckets = make([]int, len(limiter.rateLimits))
+		for index, rateLimit := range limiter.rateLimits {
+			buckets[index] = rateLimit.BucketMaxSize
+		}
+		limiter.buckets[groupKey] = buckets
+	}
+
+	for index := range limiter.rateLimits {
+		if *limiter.bucket(buckets, index) <= 0 {
+			return false
+		}
+	}
+	for index := range limiter.rateLimits {
+		*limiter.bucket(buckets, index)--
+	}
+	return true
+}
+
+// Close stops the ticker task, if any, and drops the buckets of all the scenarios
+func (rl *RateLimiter) Close() {
+	if rl.tickerTask != nil {
+		rl.tickerTask.Stop()
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for scenarioId := range rl.scenarios {
+		delete(rl.scenarios, scenarioId)
+	}
+}
+
+func (rl *RateLimiter) newScenarioLimiter(scenario *model.Scenario) *scenarioLimiter {
+	limiter := &scenarioLimiter{
+		version:    scenario.Version,
+		rateLimits: make([]model.RateLimit, 0, len(scenario.RateLimit)),
+		buckets:    make(map[string][]int),
+	}
+
+	now := rl.now()
+	for _, rateLimit := range scenario.RateLimit {
+		tickDuration, err := time.ParseDuration(rateLimit.TickDuration)
+		if err != nil || tickDuration <= 0 {
+			zkLogger.Error(LogTag, "Ignoring rate limit with invalid tick duration for scenario: ", scenario.Id, rateLimit.TickDuration, err)
+			continue
+		}
+		limiter.rateLimits = append(limiter.rateLimits, rateLimit)
+		limiter.tickDurations = append(limiter.tickDurations, tickDuration)
+		limiter.lastTicks = append(limiter.lastTicks, now)
+		limiter.scenarioBuckets = append(limiter.scenarioBuckets, rateLimit.BucketMaxSize)
+	}
+	return limiter
+}
+
+// bucket returns the tokens of the rate limit at index, from the buckets of a group key or of the scenario as per the
+// scope of the rate limit
+func (limiter *scenarioLimiter) bucket(buckets []int, index int) *int {
+	if limiter.rateLimits[index].Scope == model.RateLimitPerScenario {
+		return &limiter.scenarioBuckets[index]
+	}
+	return &buckets[index]
+}
+
+// refillElapsedTicks refills each bucket of the scenario with the tokens of the ticks since its last refill
+func (rl *RateLimiter) refillElapsedTicks(limiter *scenarioLimiter) {
+	now := rl.now()
+	for index, tickDuration := range limiter.tickDurations {
+		ticks := now.Sub(limiter.lastTicks[index]) / tickDuration
+		if ticks <= 0 {
+			continue
+		}
+		limiter.lastTicks[index] = limiter.lastTicks[index].Add(ticks * tickDuration)
+		rl.refill(limiter, index, int(ticks))
+	}
+}
+
+// refill adds the refill tokens of a number of ticks to the buckets of the rate limit at index. Group keys whose
+// buckets are all full are dropped, as they are the same as a new group key.
+func (rl *RateLimiter) refill(limiter *scenarioLimiter, index int, ticks int) {
+	rateLimit := limiter.rateLimits[index]
+	addTokens := func(tokens *int) {
+		*tokens += rateLimit.BucketRefillSize * ticks
+		if *tokens > rateLimit.BucketMaxSize {
+			*tokens = rateLimit.BucketMaxSize
+		}
+	}
+
+	if rateLimit.Scope == model.RateLimitPerScenario {
+		addTokens(&limiter.scenarioBuckets[index])
+		return
+	}
+
+	for groupKey, buckets := range limiter.buckets {
+		addTokens(&buckets[index])
+
+		full := true
+		for bucketIndex, tokens := range buckets {
+			if tokens < limiter.rateLimits[bucketIndex].BucketMaxSize {
+				full = false
+				break
+			}
+		}
+		if full {
+			delete(limiter.buckets, groupKey)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package replay
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/podDetails"
+	"github.com/zerok-ai/zk-utils-go/proto/enrichedSpan"
+	"github.com/zerok-ai/zk-utils-go/scenario"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+var LogTag = "scenario_replay"
+
+const defaultMaxSampleTraceIds = 5
+
+// AttrStoreKeyFunc returns the attribute store key to evaluate a span with
+type AttrStoreKeyFunc func(span *enrichedSpan.OtelEnrichedRawSpan) (cache.AttribStoreKey, error)
+
+// Config is the setup of a Replayer
+type Config struct {
+	// Scenarios are the scenarios to replay the spans against
+	Scenarios map[string]*model.Scenario
+
+	// Attributes is the data of the executor attribute store, by executor_version_protocol key
+	Attributes map[string]map[string]string
+
+	// PodDetails are the details of the pods, by ip
+	PodDetails map[string]podDetails.PodDetails
+
+	// AttrStoreKey returns the attribute store key of a span. Use FixedAttrStoreKey when all the spans are from the
+	// same executor, version and protocol.
+	AttrStoreKey AttrStoreKeyFunc
+
+	// MaxSampleTraceIds is the number of trace ids kept for each workload and scenario. It defaults to 5.
+	MaxSampleTraceIds int
+}
+
+// FixedAttrStoreKey returns an AttrStoreKeyFunc which gives the same key, like `OTEL_1.21.0_HTTP`, for all the spans
+func FixedAttrStoreKey(key string) (AttrStoreKeyFunc, error) {
+	attrStoreKey, err := cache.ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return func(span *enrichedSpan.OtelEnrichedRawSpan) (cache.AttribStoreKey, error) {
+		return attrStoreKey, nil
+	}, nil
+}
+
+// Replayer evaluates recorded spans against scenarios, to find out how many spans a scenario would have matched
+// before enabling it. The workloads of each span are evaluated with the RuleEvaluator, and the scenario filters are
+// evaluated once all the spans of a trace have been added, using the in-memory attribute and pod details stores of
+// the Config instead of redis. The workload ids recorded in the spans are ignored.
+type Replayer struct {
+	scenarios         map[string]*model.Scenario
+	matcher           *scenario.WorkloadMatcher
+	attrStoreKey      AttrStoreKeyFunc
+	maxSampleTraceIds int
+
+	spans        int
+	skippedSpans int
+	traceIds     []string
+	traces       map[string][]scenario.WorkloadSpan
+	workloads    map[string]*workloadCount
+}
+
+type workloadCount struct {
+	spans    int
+	traces   map[string]bool
+	traceIds []string
+}
+
+// Report is the result of a replay
+type Report struct {
+	Spans        int `json:"spans"`
+	SkippedSpans int `json:"skipped_spans"`
+	Traces       int `json:"traces"`
+
+	// Workloads are the workloads which matched at least one span, sorted by id
+	Workloads []WorkloadReport `json:"workloads"`
+
+	// Scenarios are all the scenarios, sorted by id
+	Scenarios []ScenarioReport `json:"scenarios"`
+}
+
+// WorkloadReport is the number of spans and traces matched by a workload
+type WorkloadReport struct {
+	WorkloadId     string   `json:"workload_id"`
+	Spans          int      `json:"spans"`
+	Traces         int      `json:"traces"`
+	SampleTraceIds []string `json:"sample_trace_ids"`
+}
+
+// ScenarioReport is the number of traces matched by a scenario
+type ScenarioReport struct {
+	ScenarioId     string   `json:"scenario_id"`
+	Title          string   `json:"scenario_title"`
+	Traces         int      `json:"traces"`
+	SampleTraceIds []string `json:"sample_trace_ids"`
+}
+
+// NewReplayer creates a replayer for the scenarios and stores in config
+func NewReplayer(config Config) (*Replayer, error) {
+	if config.AttrStoreKey == nil {
+		return nil, fmt.Errorf("replay config has no attribute store key function")
+	}
+
+	podDetailsData := make(map[string]map[string]string, len(config.PodDetails))
+	for ip, details := range config.PodDetails {
+		hash, err := podDetailsHash(details)
+		if err != nil {
+			return nil, fmt.Errorf("error converting pod details for ip %s: %v", ip, err)
+		}
+		podDetailsData[ip] = hash
+	}
+
+	ruleEvaluator := evaluators.NewRuleEvaluator(stores.NewMemoryExecutorAttrStore(config.Attributes), stores.NewMemoryHSetStore(podDetailsData))
+
+	maxSampleTraceIds := config.MaxSampleTraceIds
+	if maxSampleTraceIds <= 0 {
+		maxSampleTraceIds = defaultMaxSampleTraceIds
+	}
+
+	return &Replayer{
+		scenarios:         config.Scenarios,
+		matcher:           scenario.NewWorkloadMatcher(ruleEvaluator, config.Scenarios),
+		attrStoreKey:      config.AttrStoreKey,
+		maxSampleTraceIds: maxSampleTraceIds,
+		traces:            make(map[string][]scenario.WorkloadSpan),
+		workloads:         make(map[string]*workloadCount),
+	}, nil
+}
+
+// AddSpan evaluates the workloads for the span. Spans without an otlp span or an attribute store key are skipped.
+func (r *Replayer) AddSpan(span *enrichedSpan.OtelEnrichedRawSpan) {
+	r.spans++
+	if span == nil || span.Span == nil {
+		r.skippedSpans++
+		return
+	}
+
+	attrStoreKey, err := r.attrStoreKey(span)
+	if err != nil {
+		zkLogger.Error(LogTag, "Skipping span without an attribute store key: ", hex.EncodeToString(span.Span.SpanId), err)
+		r.skippedSpans++
+		return
+	}
+
+	traceId := hex.EncodeToString(span.Span.TraceId)
+	spans, ok := r.traces[traceId]
+	if !ok {
+		r.traceIds = append(r.traceIds, traceId)
+	}
+
+	workloadIds := r.matcher.Match(attrStoreKey, span.GetValueStore())
+	r.traces[traceId] = append(spans, scenario.NewWorkloadSpan(span.Span, workloadIds))
+
+	for _, workloadId := range workloadIds {
+		count, ok := r.workloads[workloadId]
+		if !ok {
+			count = &workloadCount{traces: make(map[string]bool)}
+			r.workloads[workloadId] = count
+		}
+		count.spans++
+		if !count.traces[traceId] {
+			count.traces[traceId] = true
+			count.traceIds = append(count.traceIds, traceId)
+		}
+	}
+}
+
+// Read adds the spans of a recording, see ReadSpans
+func (r *Replayer) Read(reader io.Reader, format Format) error {
+	return ReadSpans(reader, format, r.AddSpan)
+}
+
+// ReadFile adds the spans of a recording file, see ReadSpans
+func (r *Replayer) ReadFile(path string, format Format) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.Read(file, format)
+}
+
+// Report evaluates the scenario filters for the traces of the spans added so far, and returns the match counts
+func (r *Replayer) Report() Report {
+	report := Report{
+		Spans:        r.spans,
+		SkippedSpans: r.skippedSpans,
+		Traces:       len(r.traceIds),
+		Workloads:    make([]WorkloadReport, 0, len(r.workloads)),
+		Scenarios:    make([]ScenarioReport, 0, len(r.scenarios)),
+	}
+
+	for workloadId, count := range r.workloads {
+		report.Workloads = append(report.Workloads, WorkloadReport{
+			WorkloadId:     workloadId,
+			Spans:          count.spans,
+			Traces:         len(count.traceIds),
+			SampleTraceIds: r.samples(count.traceIds),
+		})
+	}
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		return report.Workloads[i].WorkloadId < report.Workloads[j].WorkloadId
+	})
+
+	for _, sc := range r.scenarios {
+		if sc == nil {
+			continue
+		}
+		scenarios := map[string]*model.Scenario{sc.Id: sc}
+
+		traceIds := make([]string, 0)
+		for _, traceId := range r.traceIds {
+			matchingScenarios, _ := scenario.FindMatchingScenariosForSpans(r.traces[traceId], scenarios)
+			if len(matchingScenarios) > 0 {
+				traceIds = append(traceIds, traceId)
+			}
+		}
+		report.Scenarios = append(report.Scenarios, ScenarioReport{
+			ScenarioId:     sc.Id,
+			Title:          sc.Title,
+			Traces:         len(traceIds),
+			SampleTraceIds: r.samples(traceIds),
+		})
+	}
+	sort.Slice(report.Scenarios, func(i, j int) bool {
+		return report.Scenarios[i].ScenarioId < report.Scenarios[j].ScenarioId
+	})
+
+	return report
+}
+
+func (r *Replayer) samples(traceIds []string) []string {
+	if len(traceIds) > r.maxSampleTraceIds {
+		traceIds = traceIds[:r.maxSampleTraceIds]
+	}
+	return append([]string{}, traceIds...)
+}
+
+func (report Report) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("spans: %d, skipped spans: %d, traces: %d\n", report.Spans, report.SkippedSpans, report.Traces))
+	for _, sc := range report.Scenarios {
+		builder.WriteString(fmt.Sprintf("scenario %s (%s): %d traces %v\n", sc.ScenarioId, sc.Title, sc.Traces, sc.SampleTraceIds))
+	}
+	for _, workload := range report.Workloads {
+		builder.WriteString(fmt.Sprintf("workload %s: %d spans, %d traces %v\n", workload.WorkloadId, workload.Spans, workload.Traces, workload.SampleTraceIds))
+	}
+	return builder.String()
+}
+
+// podDetailsHash converts the pod details to the hash stored in the pod details db, with the json of each part
+func podDetailsHash(details podDetails.PodDetails) (map[string]string, error) {
+	parts := map[string]interface{}{
+		"metadata":  details.Metadata,
+		"spec":      details.Spec,
+		"status":    details.Status,
+		"telemetry": details.Telemetry,
+	}
+
+	hash := make(map[string]string, len(parts))
+	for field, part := range parts {
+		jStr, err := json.Marshal(part)
+		if err != nil {
+			return nil, err
+		}
+		hash[field] = string(jStr)
+	}
+	return hash, nil
+}
@@ -0,0 +1,69 @@
+package replay
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	protoSpan "github.com/zerok-ai/zk-utils-go/proto"
+	"github.com/zerok-ai/zk-utils-go/proto/enrichedSpan"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"io"
+)
+
+// Format is the type of the protobuf messages in a recording
+type Format string
+
+const (
+	// FormatEnrichedSpans is a recording of OtelEnrichedRawSpanForProto messages
+	FormatEnrichedSpans Format = "enriched_spans"
+
+	// FormatBadgerResponseList is a recording of BadgerResponseList messages, as returned by the badger store. Only
+	// the otel spans in the response list are read.
+	FormatBadgerResponseList Format = "badger_response_list"
+)
+
+// ReadSpans reads the spans in a recording and calls fn for each. The messages in a recording are size-delimited,
+// each prefixed with its size as a varint, as written by WriteMessages.
+func ReadSpans(reader io.Reader, format Format, fn func(span *enrichedSpan.OtelEnrichedRawSpan)) error {
+	if format != FormatEnrichedSpans && format != FormatBadgerResponseList {
+		return fmt.Errorf("unknown recording format %s", format)
+	}
+
+	bufferedReader := bufio.NewReader(reader)
+	for index := 0; ; index++ {
+		var err error
+		if format == FormatEnrichedSpans {
+			span := &protoSpan.OtelEnrichedRawSpanForProto{}
+			if err = protodelim.UnmarshalFrom(bufferedReader, span); err == nil {
+				fn(enrichedSpan.GetEnrichedSpan(span))
+			}
+		} else {
+			responseList := &protoSpan.BadgerResponseList{}
+			if err = protodelim.UnmarshalFrom(bufferedReader, responseList); err == nil {
+				for _, response := range responseList.ResponseList {
+					if response.GetValue() != nil {
+						fn(enrichedSpan.GetEnrichedSpan(response.Value))
+					}
+				}
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading message %d of the recording: %v", index, err)
+		}
+	}
+}
+
+// WriteMessages writes the messages size-delimited, in the format read by ReadSpans
+func WriteMessages[T proto.Message](writer io.Writer, messages ...T) error {
+	for _, message := range messages {
+		if _, err := protodelim.MarshalTo(writer, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	RuleAdded   = "added"
+	RuleRemoved = "removed"
+	RuleChanged = "changed"
+)
+
+// ScenarioDiff lists the differences between two versions of a scenario
+type ScenarioDiff struct {
+	Id         string `json:"scenario_id"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+
+	// ChangedFields are the changed top level fields other than the workloads, filter, group by and rate limits
+	ChangedFields []string `json:"changed_fields,omitempty"`
+
+	AddedWorkloads   []string       `json:"added_workloads,omitempty"`
+	RemovedWorkloads []string       `json:"removed_workloads,omitempty"`
+	ChangedWorkloads []WorkloadDiff `json:"changed_workloads,omitempty"`
+
+	FilterChanged bool `json:"filter_changed,omitempty"`
+
+	AddedGroupBy      []model.GroupBy   `json:"added_group_by,omitempty"`
+	RemovedGroupBy    []model.GroupBy   `json:"removed_group_by,omitempty"`
+	AddedRateLimits   []model.RateLimit `json:"added_rate_limits,omitempty"`
+	RemovedRateLimits []model.RateLimit `json:"removed_rate_limits,omitempty"`
+}
+
+// WorkloadDiff lists the differences between two versions of a workload
+type WorkloadDiff struct {
+	WorkloadId string `json:"workload_id"`
+
+	// ChangedFields are the changed fields of the workload other than the rule
+	ChangedFields []string     `json:"changed_fields,omitempty"`
+	RuleChanges   []RuleChange `json:"rule_changes,omitempty"`
+}
+
+// RuleChange is a rule which was added, removed or changed in a workload. Path is a JSON pointer to the rule in the
+// workload rule. Rules are compared by their position, a rule group whose condition changed is reported as changed
+// and its rules are compared further.
+type RuleChange struct {
+	Path   string      `json:"path"`
+	Change string      `json:"change"`
+	Old    *model.Rule `json:"old,omitempty"`
+	New    *model.Rule `json:"new,omitempty"`
+}
+
+// DiffScenarios returns the differences between the old and new versions of a scenario. Unlike Scenario.Equals, it
+// does not modify the scenarios.
+func DiffScenarios(old, new model.Scenario) ScenarioDiff {
+	diff := ScenarioDiff{Id: new.Id, OldVersion: old.Version, NewVersion: new.Version}
+
+	if old.Id != new.Id {
+		diff.ChangedFields = append(diff.ChangedFields, "scenario_id")
+	}
+	if old.Title != new.Title {
+		diff.ChangedFields = append(diff.ChangedFields, "scenario_title")
+	}
+	if old.Type != new.Type {
+		diff.ChangedFields = append(diff.ChangedFields, "scenario_type")
+	}
+	if old.Enabled != new.Enabled {
+		diff.ChangedFields = append(diff.ChangedFields, "enabled")
+	}
+
+	diffWorkloads(&diff, old.Workloads, new.Workloads)
+	diff.FilterChanged = !filtersEqual(old.Filter, new.Filter)
+	diff.AddedGroupBy, diff.RemovedGroupBy = diffSlices(old.GroupBy, new.GroupBy)
+	diff.AddedRateLimits, diff.RemovedRateLimits = diffSlices(old.RateLimit, new.RateLimit)
+
+	return diff
+}
+
+// IsEmpty returns true if the versions of the scenario are the same apart from the version
+func (diff ScenarioDiff) IsEmpty() bool {
+	return len(diff.ChangedFields) == 0 && len(diff.AddedWorkloads) == 0 && len(diff.RemovedWorkloads) == 0 &&
+		len(diff.ChangedWorkloads) == 0 && !diff.FilterChanged && len(diff.AddedGroupBy) == 0 &&
+		len(diff.RemovedGroupBy) == 0 && len(diff.AddedRateLimits) == 0 && len(diff.RemovedRateLimits) == 0
+}
+
+// AffectedWorkloads returns the ids of the workloads which were added, removed or changed
+func (diff ScenarioDiff) AffectedWorkloads() []string {
+	workloadIds := make([]string, 0)
+	workloadIds = append(workloadIds, diff.AddedWorkloads...)
+	workloadIds = append(workloadIds, diff.RemovedWorkloads...)
+	for _, workloadDiff := range diff.ChangedWorkloads {
+		workloadIds = append(workloadIds, workloadDiff.WorkloadId)
+	}
+	sort.Strings(workloadIds)
+	return workloadIds
+}
+
+func (diff ScenarioDiff) String() string {
+	changes := make([]string, 0)
+	if len(diff.ChangedFields) > 0 {
+		changes = append(changes, "changed fields "+strings.Join(diff.ChangedFields, ", "))
+	}
+	if len(diff.AddedWorkloads) > 0 {
+		changes = append(changes, "added workloads "+strings.Join(diff.AddedWorkloads, ", "))
+	}
+	if len(diff.RemovedWorkloads) > 0 {
+		changes = append(changes, "removed workloads "+strings.Join(diff.RemovedWorkloads, ", "))
+	}
+	for _, workloadDiff := range diff.ChangedWorkloads {
+		changes = append(changes, workloadDiff.String())
+	}
+	if diff.FilterChanged {
+		changes = append(changes, "changed filter")
+	}
+	if len(diff.AddedGroupBy) > 0 || len(diff.RemovedGroupBy) > 0 {
+		changes = append(changes, fmt.Sprintf("group by +%d -%d", len(diff.AddedGroupBy), len(diff.RemovedGroupBy)))
+	}
+	if len(diff.AddedRateLimits) > 0 || len(diff.RemovedRateLimits) > 0 {
+		changes = append(changes, fmt.Sprintf("rate limits +%d -%d", len(diff.AddedRateLimits), len(diff.RemovedRateLimits)))
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "no changes")
+	}
+	return fmt.Sprintf("scenario %s %s -> %s: %s", diff.Id, diff.OldVersion, diff.NewVersion, strings.Join(changes, "; "))
+}
+
+func (workloadDiff WorkloadDiff) String() string {
+	changes := make([]string, 0, len(workloadDiff.ChangedFields)+len(workloadDiff.RuleChanges))
+	changes = append(changes, workloadDiff.ChangedFields...)
+	for _, ruleChange := range workloadDiff.RuleChanges {
+		changes = append(changes, fmt.Sprintf("rule %s %s", ruleChange.Path, ruleChange.Change))
+	}
+	return fmt.Sprintf("changed workload %s (%s)", workloadDiff.WorkloadId, strings.Join(changes, ", "))
+}
+
+func diffWorkloads(diff *ScenarioDiff, oldWorkloads, newWorkloads *map[string]model.Workload) {
+	oldMap := map[string]model.Workload{}
+	if oldWorkloads != nil {
+		oldMap = *oldWorkloads
+	}
+	newMap := map[string]model.Workload{}
+	if newWorkloads != nil {
+		newMap = *newWorkloads
+	}
+
+	for workloadId, oldWorkload := range oldMap {
+		newWorkload, ok := newMap[workloadId]
+		if !ok {
+			diff.RemovedWorkloads = append(diff.RemovedWorkloads, workloadId)
+			continue
+		}
+		workloadDiff := diffWorkload(workloadId, oldWorkload, newWorkload)
+		if len(workloadDiff.ChangedFields) > 0 || len(workloadDiff.RuleChanges) > 0 {
+			diff.ChangedWorkloads = append(diff.ChangedWorkloads, workloadDiff)
+		}
+	}
+	for workloadId := range newMap {
+		if _, ok := oldMap[workloadId]; !ok {
+			diff.AddedWorkloads = append(diff.AddedWorkloads, workloadId)
+		}
+	}
+
+	sort.Strings(diff.AddedWorkloads)
+	sort.Strings(diff.RemovedWorkloads)
+	sort.Slice(diff.ChangedWorkloads, func(i, j int) bool {
+		return diff.ChangedWorkloads[i].WorkloadId < diff.ChangedWorkloads[j].WorkloadId
+	})
+}
+
+func diffWorkload(workloadId string, old, new model.Workload) WorkloadDiff {
+	workloadDiff := WorkloadDiff{WorkloadId: workloadId}
+	if old.Executor != new.Executor {
+		workloadDiff.ChangedFields = append(workloadDiff.ChangedFields, "executor")
+	}
+	if old.Service != new.Service {
+		workloadDiff.ChangedFields = append(workloadDiff.ChangedFields, "service")
+	}
+	if old.TraceRole != new.TraceRole {
+		workloadDiff.ChangedFields = append(workloadDiff.ChangedFields, "trace_role")
+	}
+	if old.Protocol != new.Protocol {
+		workloadDiff.ChangedFields = append(workloadDiff.ChangedFields, "protocol")
+	}
+	workloadDiff.RuleChanges = diffRules(old.Rule, new.Rule, "", make([]RuleChange, 0))
+	return workloadDiff
+}
+
+// diffRules compares the rules at the same position in the old and new rule trees
+func diffRules(old, new model.Rule, path string, changes []RuleChange) []RuleChange {
+	oldIsGroup := old.Type == model.RULE_GROUP && old.RuleGroup != nil
+	newIsGroup := new.Type == model.RULE_GROUP && new.RuleGroup != nil
+
+	if !oldIsGroup || !newIsGroup {
+		if !rulesEqual(old, new) {
+			changes = append(changes, RuleChange{Path: path, Change: RuleChanged, Old: copyRule(old), New: copyRule(new)})
+		}
+		return changes
+	}
+
+	if !conditionsEqual(old.Condition, new.Condition) {
+		changes = append(changes, RuleChange{Path: path, Change: RuleChanged, Old: copyRule(old), New: copyRule(new)})
+	}
+
+	for index := 0; index < len(old.Rules) || index < len(new.Rules); index++ {
+		rulePath := path + "/rules/" + strconv.Itoa(index)
+		switch {
+		case index >= len(new.Rules):
+			changes = append(changes, RuleChange{Path: rulePath, Change: RuleRemoved, Old: copyRule(old.Rules[index])})
+		case index >= len(old.Rules):
+			changes = append(changes, RuleChange{Path: rulePath, Change: RuleAdded, New: copyRule(new.Rules[index])})
+		default:
+			changes = diffRules(old.Rules[index], new.Rules[index], rulePath, changes)
+		}
+	}
+	return changes
+}
+
+// rulesEqual compares rules by their json, which does not modify them unlike Rule.Equals
+func rulesEqual(old, new model.Rule) bool {
+	oldJson, oldErr := json.Marshal(old)
+	newJson, newErr := json.Marshal(new)
+	return oldErr == nil && newErr == nil && string(oldJson) == string(newJson)
+}
+
+func conditionsEqual(old, new *model.Condition) bool {
+	if old == nil || new == nil {
+		return old == nil && new == nil
+	}
+	return *old == *new
+}
+
+// filtersEqual compares copies of the filters, as Filter.Equals sorts the workload ids in place
+func filtersEqual(old, new model.Filter) bool {
+	var oldCopy, newCopy model.Filter
+	if copyJson(old, &oldCopy) != nil || copyJson(new, &newCopy) != nil {
+		return false
+	}
+	return oldCopy.Equals(newCopy)
+}
+
+func copyRule(rule model.Rule) *model.Rule {
+	var ruleCopy model.Rule
+	if err := copyJson(rule, &ruleCopy); err != nil {
+		return &rule
+	}
+	return &ruleCopy
+}
+
+func copyJson(value interface{}, copyValue interface{}) error {
+	jStr, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jStr, copyValue)
+}
+
+// diffSlices returns the items of new which are not in old and the items of old which are not in new, counting
+// repeated items
+func diffSlices[T comparable](old, new []T) (added []T, removed []T) {
+	counts := make(map[T]int)
+	for _, item := range old {
+		counts[item]++
+	}
+	for _, item := range new {
+		if counts[item] > 0 {
+			counts[item]--
+			continue
+		}
+		added = append(added, item)
+	}
+	for _, item := range old {
+		if counts[item] > 0 {
+			counts[item]--
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
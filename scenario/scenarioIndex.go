@@ -0,0 +1,222 @@
+package scenario
+
+import (
+	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"sort"
+	"sync"
+)
+
+// MatchedScenario identifies a scenario returned by ScenarioIndex. Titles are not unique, so the id is returned too.
+type MatchedScenario struct {
+	Id    string `json:"scenario_id"`
+	Title string `json:"scenario_title"`
+}
+
+// ScenarioIndex finds the scenarios whose filters are satisfied by a set of workload ids. It maps every workload id
+// to the scenarios whose filters reference it, so that only those filters are evaluated.
+//
+// A filter which references none of the present workloads evaluates the same as it does for an empty set of
+// workloads. Scenarios whose filters are satisfied by an empty set (for example `NOT(workload)`) are therefore
+// returned without evaluating their filters when none of their workloads are present.
+type ScenarioIndex struct {
+	mutex sync.RWMutex
+
+	// scenarios are the scenarios indexed, by their key in the scenario store
+	scenarios map[string]*model.Scenario
+
+	// indexed records the version and filter workload ids of the scenarios when they were indexed
+	indexed map[string]indexedScenario
+
+	// scenarioKeysByWorkloadId maps a workload id to the keys of the scenarios whose filters reference it
+	scenarioKeysByWorkloadId map[string]map[string]bool
+
+	// matchingWithoutWorkloads are the keys of the scenarios whose filters are satisfied by an empty set of workloads
+	matchingWithoutWorkloads map[string]bool
+}
+
+type indexedScenario struct {
+	version     string
+	workloadIds map[string]bool
+
+	// hasSpanFilter is true if the filter has a sequence, count or descendant filter, which needs the spans of a trace
+	hasSpanFilter bool
+}
+
+// NewScenarioIndex creates an index of the scenarios, typically VersionedStore.GetAllValues()
+func NewScenarioIndex(scenarios map[string]*model.Scenario) *ScenarioIndex {
+	index := &ScenarioIndex{
+		scenarios:                make(map[string]*model.Scenario),
+		indexed:                  make(map[string]indexedScenario),
+		scenarioKeysByWorkloadId: make(map[string]map[string]bool),
+		matchingWithoutWorkloads: make(map[string]bool),
+	}
+	index.Refresh(scenarios)
+	return index
+}
+
+// Refresh updates the index with the latest scenarios. Only the scenarios which were added, removed or replaced are
+// re-indexed. VersionedStore keeps the same pointer for a scenario until its version changes, so calling Refresh with
+// VersionedStore.GetAllValues() after every refresh of the store re-indexes only the scenarios that changed.
+func (index *ScenarioIndex) Refresh(scenarios map[string]*model.Scenario) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	for key := range index.scenarios {
+		if newScenario, ok := scenarios[key]; !ok || !index.isIndexed(key, newScenario) {
+			index.remove(key)
+		}
+	}
+
+	for key, scenario := range scenarios {
+		if scenario == nil {
+			continue
+		}
+		if _, ok := index.scenarios[key]; ok {
+			continue
+		}
+		index.add(key, scenario)
+	}
+}
+
+// FindMatchingScenarios returns the scenarios whose filters are satisfied by the workload ids, sorted by id.
+// Scenarios with sequence, count or descendant filters need the spans of the trace and are never returned; use
+// FindMatchingScenariosForSpans for them.
+func (index *ScenarioIndex) FindMatchingScenarios(workloadIds []string) []MatchedScenario {
+	var workloadIdMap = make(map[string]bool)
+	for _, workloadId := range workloadIds {
+		workloadIdMap[workloadId] = true
+	}
+	return index.findMatchingScenarios(workloadIdMap, nil)
+}
+
+// FindMatchingScenariosForSpans returns the scenarios whose filters are satisfied by the spans of a trace, sorted by
+// id. Unlike FindMatchingScenarios, it evaluates the sequence, count and descendant filters too.
+func (index *ScenarioIndex) FindMatchingScenariosForSpans(spans []WorkloadSpan) []MatchedScenario {
+	trace := newTraceSpans(spans)
+	return index.findMatchingScenarios(trace.workloadIdMap, trace)
+}
+
+// findMatchingScenarios evaluates the filters of the scenarios referencing the workload ids. trace is nil when the
+// spans are not known, in which case the scenarios with span filters are skipped.
+func (index *ScenarioIndex) findMatchingScenarios(workloadIdMap map[string]bool, trace *traceSpans) []MatchedScenario {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	matchingScenarios := make([]MatchedScenario, 0)
+	evaluated := make(map[string]bool)
+
+	for workloadId := range workloadIdMap {
+		for key := range index.scenarioKeysByWorkloadId[workloadId] {
+			if evaluated[key] {
+				continue
+			}
+			evaluated[key] = true
+			if trace == nil && index.indexed[key].hasSpanFilter {
+				continue
+			}
+
+			scenario := index.scenarios[key]
+			val, err := evaluateTraceFilter(scenario.Filter, workloadIdMap, trace)
+			if err != nil {
+				zkLogger.Error(LogTag, "Error while evaluating the filter for: ", scenario.Title, err)
+			} else if val {
+				matchingScenarios = append(matchingScenarios, newMatchedScenario(scenario))
+			}
+		}
+	}
+
+	// the filters which reference none of the workloads are satisfied only if they are satisfied by no workloads
+	for key := range index.matchingWithoutWorkloads {
+		if evaluated[key] || (trace == nil && index.indexed[key].hasSpanFilter) {
+			continue
+		}
+		matchingScenarios = append(matchingScenarios, newMatchedScenario(index.scenarios[key]))
+	}
+
+	sort.Slice(matchingScenarios, func(i, j int) bool {
+		return matchingScenarios[i].Id < matchingScenarios[j].Id
+	})
+	return matchingScenarios
+}
+
+func (index *ScenarioIndex) add(key string, scenario *model.Scenario) {
+	workloadIds := getFilterWorkloadIds(scenario.Filter, map[string]bool{})
+	index.scenarios[key] = scenario
+	index.indexed[key] = indexedScenario{
+		version:       scenario.Version,
+		workloadIds:   workloadIds,
+		hasSpanFilter: hasSpanFilter(scenario.Filter),
+	}
+
+	for workloadId := range workloadIds {
+		scenarioKeys, ok := index.scenarioKeysByWorkloadId[workloadId]
+		if !ok {
+			scenarioKeys = make(map[string]bool)
+			index.scenarioKeysByWorkloadId[workloadId] = scenarioKeys
+		}
+		scenarioKeys[key] = true
+	}
+
+	// a trace without spans is an empty set of workloads for span filters too
+	val, err := evaluateTraceFilter(scenario.Filter, map[string]bool{}, newTraceSpans(nil))
+	if err != nil {
+		zkLogger.Error(LogTag, "Error while evaluating the filter for: ", scenario.Title, err)
+	} else if val {
+		index.matchingWithoutWorkloads[key] = true
+	}
+}
+
+func (index *ScenarioIndex) remove(key string) {
+	workloadIds := index.indexed[key].workloadIds
+	delete(index.scenarios, key)
+	delete(index.indexed, key)
+	delete(index.matchingWithoutWorkloads, key)
+
+	for workloadId := range workloadIds {
+		scenarioKeys := index.scenarioKeysByWorkloadId[workloadId]
+		delete(scenarioKeys, key)
+		if len(scenarioKeys) == 0 {
+			delete(index.scenarioKeysByWorkloadId, workloadId)
+		}
+	}
+}
+
+// getFilterWorkloadIds adds the workload ids referenced by the filter to workloadIds
+func getFilterWorkloadIds(filter model.Filter, workloadIds map[string]bool) map[string]bool {
+	if (filter.Type == model.WORKLOAD || filter.IsSpanFilter()) && filter.WorkloadIds != nil {
+		for _, workloadId := range *filter.WorkloadIds {
+			workloadIds[workloadId] = true
+		}
+	} else if filter.Type == model.FILTER && filter.Filters != nil {
+		for _, childFilter := range *filter.Filters {
+			getFilterWorkloadIds(childFilter, workloadIds)
+		}
+	}
+	return workloadIds
+}
+
+// hasSpanFilter returns true if the filter or any of its child filters is a span filter
+func hasSpanFilter(filter model.Filter) bool {
+	if filter.IsSpanFilter() {
+		return true
+	}
+	if filter.Type == model.FILTER && filter.Filters != nil {
+		for _, childFilter := range *filter.Filters {
+			if hasSpanFilter(childFilter) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIndexed returns true if the scenario is the one indexed against key. A scenario is replaced with a new pointer
+// when it changes; the version is compared too in case the caller updates the scenario in place.
+func (index *ScenarioIndex) isIndexed(key string, scenario *model.Scenario) bool {
+	return index.scenarios[key] == scenario && scenario != nil && index.indexed[key].version == scenario.Version
+}
+
+func newMatchedScenario(scenario *model.Scenario) MatchedScenario {
+	return MatchedScenario{Id: scenario.Id, Title: scenario.Title}
+}
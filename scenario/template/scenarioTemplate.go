@@ -0,0 +1,165 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/crypto"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderPattern matches `${name}` placeholders
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.\-]*)}`)
+
+// Instantiate creates a scenario from a template by replacing the `${name}` placeholders in the scenario title, the
+// workload services and the rule values with params. It returns an error listing the placeholders without a param.
+//
+// The id of the scenario is derived from the template id and the params of its Variables, so instantiating a template
+// with the same params gives the same scenario, whatever other params are passed. Every workload is stored against its
+// model.WorkLoadUUID, and the workload ids in the filter and group by are updated to match. The template is not
+// modified.
+func Instantiate(template model.Scenario, params map[string]string) (model.Scenario, error) {
+	var scenario model.Scenario
+	if err := copyScenario(template, &scenario); err != nil {
+		return model.Scenario{}, fmt.Errorf("error copying template %s: %v", template.Id, err)
+	}
+
+	r := &replacer{params: params, missing: make(map[string]bool)}
+	scenario.Title = r.replace(scenario.Title)
+
+	workloadIds := make(map[string]string)
+	if scenario.Workloads != nil {
+		workloads := make(map[string]model.Workload, len(*scenario.Workloads))
+		for workloadId, workload := range *scenario.Workloads {
+			workload.Service = r.replace(workload.Service)
+			r.replaceInRule(workload.Rule)
+
+			workloadUUID := model.WorkLoadUUID(workload).String()
+			workloadIds[workloadId] = workloadUUID
+			workloads[workloadUUID] = workload
+		}
+		scenario.Workloads = &workloads
+	}
+
+	if len(r.missing) > 0 {
+		missing := make([]string, 0, len(r.missing))
+		for name := range r.missing {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return model.Scenario{}, fmt.Errorf("missing params for template %s: %s", template.Id, strings.Join(missing, ", "))
+	}
+
+	renameFilterWorkloads(scenario.Filter, workloadIds)
+	for index, groupBy := range scenario.GroupBy {
+		if workloadUUID, ok := workloadIds[groupBy.WorkloadId]; ok {
+			scenario.GroupBy[index].WorkloadId = workloadUUID
+		}
+	}
+
+	scenario.Id = instanceId(template.Id, Variables(template), params)
+	return scenario, nil
+}
+
+// Variables returns the names of the placeholders in the template, sorted
+func Variables(template model.Scenario) []string {
+	names := make(map[string]bool)
+	collect := func(text string) {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			names[match[1]] = true
+		}
+	}
+
+	collect(template.Title)
+	if template.Workloads != nil {
+		for _, workload := range *template.Workloads {
+			collect(workload.Service)
+			forEachRuleLeaf(workload.Rule, func(leaf *model.RuleLeaf) {
+				if leaf.Value != nil {
+					collect(string(*leaf.Value))
+				}
+			})
+		}
+	}
+
+	variables := make([]string, 0, len(names))
+	for name := range names {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+type replacer struct {
+	params  map[string]string
+	missing map[string]bool
+}
+
+func (r *replacer) replace(text string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		value, ok := r.params[name]
+		if !ok {
+			r.missing[name] = true
+			return placeholder
+		}
+		return value
+	})
+}
+
+func (r *replacer) replaceInRule(rule model.Rule) {
+	forEachRuleLeaf(rule, func(leaf *model.RuleLeaf) {
+		if leaf.Value != nil {
+			value := model.ValueTypes(r.replace(string(*leaf.Value)))
+			leaf.Value = &value
+		}
+	})
+}
+
+func forEachRuleLeaf(rule model.Rule, fn func(leaf *model.RuleLeaf)) {
+	if rule.RuleLeaf != nil {
+		fn(rule.RuleLeaf)
+	}
+	if rule.RuleGroup != nil {
+		for _, childRule := range rule.Rules {
+			forEachRuleLeaf(childRule, fn)
+		}
+	}
+}
+
+func renameFilterWorkloads(filter model.Filter, workloadIds map[string]string) {
+	if filter.WorkloadIds != nil {
+		for index, workloadId := range *filter.WorkloadIds {
+			if workloadUUID, ok := workloadIds[workloadId]; ok {
+				(*filter.WorkloadIds)[index] = workloadUUID
+			}
+		}
+	}
+	if filter.Filters != nil {
+		for _, childFilter := range *filter.Filters {
+			renameFilterWorkloads(childFilter, workloadIds)
+		}
+	}
+}
+
+// instanceId hashes the template id with the params of the variables, which are sorted by name. Other params do not
+// change the scenario, so they are left out.
+func instanceId(templateId string, variables []string, params map[string]string) string {
+	builder := strings.Builder{}
+	builder.WriteString(templateId)
+	for _, name := range variables {
+		builder.WriteString("\x00" + name + "=" + params[name])
+	}
+	return crypto.CalculateHashNewSHA2(builder.String()).String()
+}
+
+// copyScenario deep copies the scenario, as the workloads, rules and filters are shared through pointers
+func copyScenario(scenario model.Scenario, scenarioCopy *model.Scenario) error {
+	jStr, err := json.Marshal(scenario)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jStr, scenarioCopy)
+}
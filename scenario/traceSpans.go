@@ -0,0 +1,163 @@
+package scenario
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	otlpTrace "go.opentelemetry.io/proto/otlp/trace/v1"
+	"sort"
+	"time"
+)
+
+// WorkloadSpan is a span of a trace along with the ids of the workloads it matched
+type WorkloadSpan struct {
+	SpanId            string
+	ParentSpanId      string
+	StartTimeUnixNano uint64
+	EndTimeUnixNano   uint64
+	WorkloadIds       []string
+}
+
+// NewWorkloadSpan creates a WorkloadSpan from an otlp span, typically OtelEnrichedRawSpan.Span and
+// OtelEnrichedRawSpan.WorkloadIdList
+func NewWorkloadSpan(span *otlpTrace.Span, workloadIds []string) WorkloadSpan {
+	return WorkloadSpan{
+		SpanId:            hex.EncodeToString(span.GetSpanId()),
+		ParentSpanId:      hex.EncodeToString(span.GetParentSpanId()),
+		StartTimeUnixNano: span.GetStartTimeUnixNano(),
+		EndTimeUnixNano:   span.GetEndTimeUnixNano(),
+		WorkloadIds:       workloadIds,
+	}
+}
+
+// traceSpans indexes the spans of a trace for evaluating span filters
+type traceSpans struct {
+	spans             []WorkloadSpan
+	workloadIdMap     map[string]bool
+	spansByWorkloadId map[string][]int
+	spanIndexById     map[string]int
+}
+
+func newTraceSpans(spans []WorkloadSpan) *traceSpans {
+	trace := &traceSpans{
+		spans:             spans,
+		workloadIdMap:     make(map[string]bool),
+		spansByWorkloadId: make(map[string][]int),
+		spanIndexById:     make(map[string]int),
+	}
+	for index, span := range spans {
+		trace.spanIndexById[span.SpanId] = index
+		for _, workloadId := range span.WorkloadIds {
+			trace.workloadIdMap[workloadId] = true
+			trace.spansByWorkloadId[workloadId] = append(trace.spansByWorkloadId[workloadId], index)
+		}
+	}
+	return trace
+}
+
+func (trace *traceSpans) evaluate(filter model.Filter) (bool, error) {
+	if filter.WorkloadIds == nil || len(*filter.WorkloadIds) == 0 {
+		return false, fmt.Errorf("filter of type %s has no workload ids", filter.Type)
+	}
+	workloadIds := *filter.WorkloadIds
+
+	within, err := filter.GetWithin()
+	if err != nil {
+		return false, fmt.Errorf("invalid within %q: %v", *filter.Within, err)
+	}
+
+	switch filter.Type {
+	case model.SEQUENCE:
+		return trace.matchChain(workloadIds, func(previous int, next int) bool {
+			return trace.follows(previous, next, within)
+		}), nil
+	case model.DESCENDANT:
+		return trace.matchChain(workloadIds, trace.isDescendant), nil
+	case model.COUNT:
+		return trace.countWithin(workloadIds, within) >= filter.GetMinCount(), nil
+	}
+	return false, fmt.Errorf("unknown span filter type %s", filter.Type)
+}
+
+// matchChain returns true if there is a span for every workload such that each span is linked to the span of the
+// previous workload
+func (trace *traceSpans) matchChain(workloadIds []string, linked func(previous int, next int) bool) bool {
+	candidates := trace.spansByWorkloadId[workloadIds[0]]
+	for _, workloadId := range workloadIds[1:] {
+		nextCandidates := make([]int, 0)
+		for _, next := range trace.spansByWorkloadId[workloadId] {
+			for _, previous := range candidates {
+				if previous != next && linked(previous, next) {
+					nextCandidates = append(nextCandidates, next)
+					break
+				}
+			}
+		}
+		candidates = nextCandidates
+	}
+	return len(candidates) > 0
+}
+
+// follows returns true if the span at next starts after the span at previous ends, within the given duration if
+// it is not 0
+func (trace *traceSpans) follows(previous int, next int, within time.Duration) bool {
+	previousEnd := trace.spans[previous].EndTimeUnixNano
+	nextStart := trace.spans[next].StartTimeUnixNano
+	if nextStart < previousEnd {
+		return false
+	}
+	return within <= 0 || nextStart-previousEnd <= uint64(within.Nanoseconds())
+}
+
+// isDescendant returns true if the span at ancestor is a parent, grand parent and so on of the span at index
+func (trace *traceSpans) isDescendant(ancestor int, index int) bool {
+	ancestorId := trace.spans[ancestor].SpanId
+
+	// a trace has no more ancestors than spans, the limit guards against cycles in malformed traces
+	for step := 0; step < len(trace.spans); step++ {
+		parentId := trace.spans[index].ParentSpanId
+		if parentId == "" {
+			return false
+		}
+		if parentId == ancestorId {
+			return true
+		}
+		parentIndex, ok := trace.spanIndexById[parentId]
+		if !ok {
+			return false
+		}
+		index = parentIndex
+	}
+	return false
+}
+
+// countWithin returns the largest number of spans of the workloads which start within the given duration, or the
+// number of spans of the workloads if it is 0
+func (trace *traceSpans) countWithin(workloadIds []string, within time.Duration) int {
+	spanIndexes := make(map[int]bool)
+	for _, workloadId := range workloadIds {
+		for _, index := range trace.spansByWorkloadId[workloadId] {
+			spanIndexes[index] = true
+		}
+	}
+	if within <= 0 {
+		return len(spanIndexes)
+	}
+
+	startTimes := make([]uint64, 0, len(spanIndexes))
+	for index := range spanIndexes {
+		startTimes = append(startTimes, trace.spans[index].StartTimeUnixNano)
+	}
+	sort.Slice(startTimes, func(i, j int) bool { return startTimes[i] < startTimes[j] })
+
+	maxCount := 0
+	for first, last := 0, 0; last < len(startTimes); last++ {
+		for startTimes[last]-startTimes[first] > uint64(within.Nanoseconds()) {
+			first++
+		}
+		if last-first+1 > maxCount {
+			maxCount = last - first + 1
+		}
+	}
+	return maxCount
+}
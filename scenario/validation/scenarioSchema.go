@@ -0,0 +1,324 @@
+package validation
+
+import (
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"reflect"
+	"strings"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaNode is the schema of a json value, generated from the go type it is decoded into. The strict decoder walks
+// the same nodes that the JSON Schema is written from, so that both enforce the same contract.
+type schemaNode struct {
+	// name is set for structs, which are written to $defs
+	name string
+
+	kind       string
+	properties []*schemaProperty
+	required   []string
+
+	// requiredWhen lists the properties which are required for each value of a discriminator property
+	requiredWhen *conditionalRequired
+
+	items  *schemaNode
+	values *schemaNode
+	enum   []string
+}
+
+type schemaProperty struct {
+	name     string
+	node     *schemaNode
+	nullable bool
+}
+
+type conditionalRequired struct {
+	property string
+	values   []string
+	required map[string][]string
+}
+
+const (
+	kindObject  = "object"
+	kindMap     = "map"
+	kindArray   = "array"
+	kindString  = "string"
+	kindInteger = "integer"
+	kindNumber  = "number"
+	kindBoolean = "boolean"
+)
+
+var (
+	// enumsForType are the values allowed for string types
+	enumsForType = map[reflect.Type][]string{
+		reflect.TypeOf(model.Condition("")):      {string(model.AND), string(model.OR), string(model.NOT), string(model.NAND), string(model.NOR)},
+		reflect.TypeOf(model.ExecutorName("")):   {string(model.ExecutorEbpf), string(model.ExecutorOTel)},
+		reflect.TypeOf(model.RateLimitScope("")): {string(model.RateLimitPerGroup), string(model.RateLimitPerScenario)},
+	}
+
+	// conditionalRequiredForType are the properties which are required depending on the type of a rule or filter.
+	// They are left out of the required properties of the struct.
+	conditionalRequiredForType = map[reflect.Type]*conditionalRequired{
+		reflect.TypeOf(model.Rule{}): {
+			property: "type",
+			values:   []string{model.RULE, model.RULE_GROUP},
+			required: map[string][]string{
+				model.RULE:       {"id", "datatype", "operator", "value"},
+				model.RULE_GROUP: {"condition", "rules"},
+			},
+		},
+		reflect.TypeOf(model.Filter{}): {
+			property: "type",
+			values:   []string{model.WORKLOAD, model.FILTER, model.SEQUENCE, model.COUNT, model.DESCENDANT},
+			required: map[string][]string{
+				model.WORKLOAD:   {"condition", "workload_ids"},
+				model.FILTER:     {"condition", "filters"},
+				model.SEQUENCE:   {"workload_ids"},
+				model.COUNT:      {"workload_ids"},
+				model.DESCENDANT: {"workload_ids"},
+			},
+		},
+	}
+
+	// requiredForType are the properties which are required even though they can be omitted or null in go
+	requiredForType = map[reflect.Type][]string{
+		reflect.TypeOf(model.Scenario{}): {"workloads"},
+		reflect.TypeOf(model.Workload{}): {"rule"},
+	}
+)
+
+// ScenarioSchema returns the JSON Schema (draft 2020-12) of model.Scenario. scenario.schema.json in this package is
+// generated from it for clients which are not written in go.
+func ScenarioSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(model.Scenario{}))
+}
+
+// WorkloadSchema returns the JSON Schema (draft 2020-12) of model.Workload
+func WorkloadSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(model.Workload{}))
+}
+
+// RuleSchema returns the JSON Schema (draft 2020-12) of model.Rule
+func RuleSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(model.Rule{}))
+}
+
+// FilterSchema returns the JSON Schema (draft 2020-12) of model.Filter
+func FilterSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(model.Filter{}))
+}
+
+func generateSchema(t reflect.Type) map[string]interface{} {
+	root := newSchemaGenerator().nodeFor(t)
+
+	defs := make(map[string]interface{})
+	schema := map[string]interface{}{
+		"$schema": schemaDialect,
+		"title":   root.name,
+		"$ref":    "#/$defs/" + root.name,
+		"$defs":   defs,
+	}
+	writeDefs(root, defs)
+	return schema
+}
+
+type schemaGenerator struct {
+	structs map[reflect.Type]*schemaNode
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{structs: make(map[reflect.Type]*schemaNode)}
+}
+
+func (g *schemaGenerator) nodeFor(t reflect.Type) *schemaNode {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return g.structNode(t)
+	case reflect.Slice, reflect.Array:
+		return &schemaNode{kind: kindArray, items: g.nodeFor(t.Elem())}
+	case reflect.Map:
+		return &schemaNode{kind: kindMap, values: g.nodeFor(t.Elem())}
+	case reflect.String:
+		return &schemaNode{kind: kindString, enum: enumsForType[t]}
+	case reflect.Bool:
+		return &schemaNode{kind: kindBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schemaNode{kind: kindInteger}
+	case reflect.Float32, reflect.Float64:
+		return &schemaNode{kind: kindNumber}
+	}
+	return &schemaNode{}
+}
+
+func (g *schemaGenerator) structNode(t reflect.Type) *schemaNode {
+	if node, ok := g.structs[t]; ok {
+		return node
+	}
+
+	node := &schemaNode{name: t.Name(), kind: kindObject, requiredWhen: conditionalRequiredForType[t]}
+	g.structs[t] = node
+	g.addProperties(node, t, false)
+
+	for _, name := range requiredForType[t] {
+		if !contains(node.required, name) {
+			node.required = append(node.required, name)
+		}
+	}
+	return node
+}
+
+// addProperties adds the json properties of the struct to node. Properties are required unless they are omitted when
+// empty or can be null. Fields of embedded struct pointers are promoted by encoding/json and are optional, as the
+// pointer can be nil.
+func (g *schemaGenerator) addProperties(node *schemaNode, t reflect.Type, optional bool) {
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addProperties(node, embedded, optional || field.Type.Kind() == reflect.Pointer)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		kind := field.Type.Kind()
+		property := &schemaProperty{
+			name:     name,
+			node:     g.nodeFor(field.Type),
+			nullable: kind == reflect.Pointer || kind == reflect.Slice || kind == reflect.Map,
+		}
+		node.properties = append(node.properties, property)
+
+		omitEmpty := strings.Contains(options, "omitempty")
+		if !optional && !omitEmpty && !property.nullable && !node.isConditionallyRequired(name) {
+			node.required = append(node.required, name)
+		}
+	}
+}
+
+func (node *schemaNode) property(name string) *schemaProperty {
+	for _, property := range node.properties {
+		if property.name == name {
+			return property
+		}
+	}
+	return nil
+}
+
+func (node *schemaNode) isConditionallyRequired(name string) bool {
+	if node.requiredWhen == nil {
+		return false
+	}
+	for _, required := range node.requiredWhen.required {
+		if contains(required, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDefs adds the struct nodes reachable from node to defs
+func writeDefs(node *schemaNode, defs map[string]interface{}) {
+	if node.name != "" {
+		if _, ok := defs[node.name]; ok {
+			return
+		}
+		defs[node.name] = node.definition()
+	}
+
+	for _, property := range node.properties {
+		writeDefs(property.node, defs)
+	}
+	if node.items != nil {
+		writeDefs(node.items, defs)
+	}
+	if node.values != nil {
+		writeDefs(node.values, defs)
+	}
+}
+
+// reference is the schema used where node appears as a value
+func (node *schemaNode) reference(nullable bool) map[string]interface{} {
+	var schema map[string]interface{}
+	if node.name != "" {
+		schema = map[string]interface{}{"$ref": "#/$defs/" + node.name}
+	} else {
+		schema = node.definition()
+	}
+
+	if !nullable {
+		return schema
+	}
+	if node.name != "" {
+		return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+	}
+	schema["type"] = []string{schema["type"].(string), "null"}
+	return schema
+}
+
+func (node *schemaNode) definition() map[string]interface{} {
+	switch node.kind {
+	case kindObject:
+		properties := make(map[string]interface{})
+		for _, property := range node.properties {
+			properties[property.name] = property.node.reference(property.nullable)
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(node.required) > 0 {
+			schema["required"] = node.required
+		}
+		if node.requiredWhen != nil {
+			schema["allOf"] = node.requiredWhen.definition()
+			if discriminator, ok := properties[node.requiredWhen.property].(map[string]interface{}); ok {
+				discriminator["enum"] = node.requiredWhen.values
+			}
+		}
+		return schema
+	case kindMap:
+		return map[string]interface{}{"type": "object", "additionalProperties": node.values.reference(false)}
+	case kindArray:
+		return map[string]interface{}{"type": "array", "items": node.items.reference(false)}
+	case "":
+		return map[string]interface{}{}
+	}
+
+	schema := map[string]interface{}{"type": node.kind}
+	if len(node.enum) > 0 {
+		schema["enum"] = node.enum
+	}
+	return schema
+}
+
+func (required *conditionalRequired) definition() []interface{} {
+	conditions := make([]interface{}, 0, len(required.values))
+	for _, value := range required.values {
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{required.property: map[string]interface{}{"const": value}},
+				"required":   []string{required.property},
+			},
+			"then": map[string]interface{}{"required": required.required[value]},
+		})
+	}
+	return conditions
+}
@@ -0,0 +1,257 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationIssue is a problem found in a scenario. Location is a JSON pointer (RFC 6901) to the offending field in
+// the scenario json, so that a UI can highlight it.
+type ValidationIssue struct {
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+func (issue ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s", issue.Location, issue.Message)
+}
+
+// ValidateScenario checks the scenario without evaluating it and returns all the issues found. A scenario which
+// passes validation can be stored and compiled safely.
+func ValidateScenario(scenario model.Scenario) []ValidationIssue {
+	v := &validator{issues: make([]ValidationIssue, 0)}
+
+	if scenario.Workloads == nil || len(*scenario.Workloads) == 0 {
+		v.addIssue("/workloads", "scenario has no workloads")
+	} else {
+		// iterate in a fixed order so that the issues are reported in the same order every time
+		workloadIds := make([]string, 0, len(*scenario.Workloads))
+		for workloadId := range *scenario.Workloads {
+			workloadIds = append(workloadIds, workloadId)
+		}
+		sort.Strings(workloadIds)
+
+		for _, workloadId := range workloadIds {
+			workload := (*scenario.Workloads)[workloadId]
+			v.validateRule(workload.Rule, pointer("", "workloads", workloadId, "rule"))
+		}
+	}
+
+	v.validateFilter(scenario.Filter, scenario.Workloads, "/filter")
+
+	for index, rateLimit := range scenario.RateLimit {
+		location := pointer("", "rate_limit", strconv.Itoa(index))
+		if _, err := time.ParseDuration(rateLimit.TickDuration); err != nil {
+			v.addIssue(location+"/tick_duration", fmt.Sprintf("invalid tick duration %q: %v", rateLimit.TickDuration, err))
+		}
+		if rateLimit.BucketMaxSize <= 0 {
+			v.addIssue(location+"/bucket_max_size", "bucket_max_size must be greater than 0")
+		}
+		if rateLimit.BucketRefillSize <= 0 {
+			v.addIssue(location+"/bucket_refill_size", "bucket_refill_size must be greater than 0")
+		}
+		if !rateLimit.Scope.IsValid() {
+			v.addIssue(location+"/scope", fmt.Sprintf("unknown scope %q", rateLimit.Scope))
+		}
+	}
+
+	return v.issues
+}
+
+type validator struct {
+	issues []ValidationIssue
+}
+
+func (v *validator) addIssue(location string, message string) {
+	v.issues = append(v.issues, ValidationIssue{Location: location, Message: message})
+}
+
+func (v *validator) validateRule(rule model.Rule, location string) {
+	switch rule.Type {
+	case model.RULE_GROUP:
+		v.validateRuleGroup(rule, location)
+	case model.RULE:
+		v.validateRuleLeaf(rule, location)
+	default:
+		v.addIssue(location+"/type", fmt.Sprintf("unknown rule type %q", rule.Type))
+	}
+}
+
+func (v *validator) validateRuleGroup(rule model.Rule, location string) {
+	if rule.RuleGroup == nil || rule.Condition == nil {
+		v.addIssue(location+"/condition", "rule group has no condition")
+	} else if !rule.Condition.IsValid() {
+		v.addIssue(location+"/condition", fmt.Sprintf("unknown condition %q", *rule.Condition))
+	}
+
+	if rule.RuleGroup == nil || len(rule.Rules) == 0 {
+		v.addIssue(location+"/rules", "rule group has no rules")
+		return
+	}
+	if rule.Condition != nil && *rule.Condition == model.NOT && len(rule.Rules) != 1 {
+		v.addIssue(location+"/rules", fmt.Sprintf("condition NOT needs exactly one rule, found %d", len(rule.Rules)))
+	}
+
+	for index, childRule := range rule.Rules {
+		v.validateRule(childRule, pointer(location, "rules", strconv.Itoa(index)))
+	}
+}
+
+func (v *validator) validateRuleLeaf(rule model.Rule, location string) {
+	if rule.RuleLeaf == nil {
+		v.addIssue(location, "rule has no id, datatype, operator or value")
+		return
+	}
+
+	leaf := rule.RuleLeaf
+	if leaf.ID == nil || *leaf.ID == "" {
+		v.addIssue(location+"/id", "rule has no id")
+	} else if err := functions.ValidatePath(*leaf.ID); err != nil {
+		v.addIssue(location+"/id", err.Error())
+	}
+
+	if leaf.Value == nil {
+		v.addIssue(location+"/value", "rule has no value")
+	}
+
+	if leaf.Datatype == nil {
+		v.addIssue(location+"/datatype", "rule has no datatype")
+		return
+	}
+	operators, ok := evaluators.SupportedOperators(string(*leaf.Datatype))
+	if !ok {
+		v.addIssue(location+"/datatype", fmt.Sprintf("unknown datatype %q", *leaf.Datatype))
+		return
+	}
+
+	if !leaf.Options.IsEmpty() && !evaluators.SupportsMatchOptions(string(*leaf.Datatype)) {
+		v.addIssue(location+"/options", fmt.Sprintf("match options are not supported for datatype %q", *leaf.Datatype))
+	}
+
+	if leaf.Operator == nil {
+		v.addIssue(location+"/operator", "rule has no operator")
+		return
+	}
+	operator := string(*leaf.Operator)
+	if !contains(operators, operator) {
+		v.addIssue(location+"/operator", fmt.Sprintf("operator %q is not supported for datatype %q", operator, *leaf.Datatype))
+		return
+	}
+
+	if leaf.Value == nil {
+		return
+	}
+	value := string(*leaf.Value)
+
+	if err := evaluators.ValidateRuleValue(string(*leaf.Datatype), operator, value); err != nil {
+		v.addIssue(location+"/value", err.Error())
+	}
+}
+
+func (v *validator) validateFilter(filter model.Filter, workloads *map[string]model.Workload, location string) {
+	if filter.IsSpanFilter() {
+		v.validateSpanFilter(filter, workloads, location)
+		return
+	}
+
+	if !filter.Condition.IsValid() {
+		v.addIssue(location+"/condition", fmt.Sprintf("unknown condition %q", filter.Condition))
+	}
+	childCount := 0
+	if filter.Type == model.WORKLOAD && filter.WorkloadIds != nil {
+		childCount = len(*filter.WorkloadIds)
+	} else if filter.Type == model.FILTER && filter.Filters != nil {
+		childCount = len(*filter.Filters)
+	}
+	if filter.Condition == model.NOT && childCount > 1 {
+		v.addIssue(location+"/condition", fmt.Sprintf("condition NOT needs exactly one child, found %d", childCount))
+	}
+
+	switch filter.Type {
+	case model.WORKLOAD:
+		if filter.WorkloadIds == nil || len(*filter.WorkloadIds) == 0 {
+			v.addIssue(location+"/workload_ids", "filter has no workload ids")
+			return
+		}
+		v.validateWorkloadIds(*filter.WorkloadIds, workloads, location)
+	case model.FILTER:
+		if filter.Filters == nil || len(*filter.Filters) == 0 {
+			v.addIssue(location+"/filters", "filter has no filters")
+			return
+		}
+		for index, childFilter := range *filter.Filters {
+			v.validateFilter(childFilter, workloads, pointer(location, "filters", strconv.Itoa(index)))
+		}
+	default:
+		v.addIssue(location+"/type", fmt.Sprintf("unknown filter type %q", filter.Type))
+	}
+}
+
+// validateSpanFilter validates sequence, count and descendant filters. Their condition is not used.
+func (v *validator) validateSpanFilter(filter model.Filter, workloads *map[string]model.Workload, location string) {
+	minWorkloads := 1
+	if filter.Type == model.SEQUENCE || filter.Type == model.DESCENDANT {
+		minWorkloads = 2
+	}
+	if filter.WorkloadIds == nil || len(*filter.WorkloadIds) < minWorkloads {
+		v.addIssue(location+"/workload_ids", fmt.Sprintf("filter of type %s needs at least %d workload ids", filter.Type, minWorkloads))
+	} else {
+		v.validateWorkloadIds(*filter.WorkloadIds, workloads, location)
+	}
+
+	if filter.Within != nil {
+		if filter.Type == model.DESCENDANT {
+			v.addIssue(location+"/within", "within is not supported for descendant filters")
+		} else if within, err := filter.GetWithin(); err != nil {
+			v.addIssue(location+"/within", fmt.Sprintf("invalid duration %q: %v", *filter.Within, err))
+		} else if within <= 0 {
+			v.addIssue(location+"/within", "within must be greater than 0")
+		}
+	}
+
+	if filter.MinCount != nil {
+		if filter.Type != model.COUNT {
+			v.addIssue(location+"/min_count", fmt.Sprintf("min_count is not supported for %s filters", filter.Type))
+		} else if *filter.MinCount <= 0 {
+			v.addIssue(location+"/min_count", "min_count must be greater than 0")
+		}
+	}
+}
+
+func (v *validator) validateWorkloadIds(workloadIds model.WorkloadIds, workloads *map[string]model.Workload, location string) {
+	for index, workloadId := range workloadIds {
+		if workloads == nil {
+			v.addIssue(pointer(location, "workload_ids", strconv.Itoa(index)), fmt.Sprintf("workload %q is not defined in workloads", workloadId))
+			continue
+		}
+		if _, ok := (*workloads)[workloadId]; !ok {
+			v.addIssue(pointer(location, "workload_ids", strconv.Itoa(index)), fmt.Sprintf("workload %q is not defined in workloads", workloadId))
+		}
+	}
+}
+
+// pointer appends the tokens to the JSON pointer at base, escaping `~` and `/` in the tokens
+func pointer(base string, tokens ...string) string {
+	builder := strings.Builder{}
+	builder.WriteString(base)
+	for _, token := range tokens {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
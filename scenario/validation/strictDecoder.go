@@ -0,0 +1,319 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// DecodeError is a problem found while decoding json. Line and Column are 1-based and point at the offending value,
+// or at the start of the object for missing properties.
+type DecodeError struct {
+	ValidationIssue
+	Offset int64 `json:"offset"`
+	Line   int   `json:"line"`
+	Column int   `json:"column"`
+}
+
+func (e DecodeError) String() string {
+	location := e.Location
+	if location == "" {
+		location = "/"
+	}
+	return fmt.Sprintf("line %d, column %d (%s): %s", e.Line, e.Column, location, e.Message)
+}
+
+// DecodeErrors are all the problems found while decoding json
+type DecodeErrors []DecodeError
+
+func (errs DecodeErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// DecodeScenario decodes a scenario, checking it against ScenarioSchema. Unlike json.Unmarshal, it rejects unknown
+// properties, values of the wrong type and missing required properties. The error is a DecodeErrors with all the
+// problems found.
+func DecodeScenario(data []byte) (model.Scenario, error) {
+	var scenario model.Scenario
+	err := decodeStrict(data, &scenario)
+	return scenario, err
+}
+
+// DecodeWorkload decodes a workload, checking it against WorkloadSchema. See DecodeScenario.
+func DecodeWorkload(data []byte) (model.Workload, error) {
+	var workload model.Workload
+	err := decodeStrict(data, &workload)
+	return workload, err
+}
+
+// DecodeRule decodes a rule, checking it against RuleSchema. See DecodeScenario.
+func DecodeRule(data []byte) (model.Rule, error) {
+	var rule model.Rule
+	err := decodeStrict(data, &rule)
+	return rule, err
+}
+
+// DecodeFilter decodes a filter, checking it against FilterSchema. See DecodeScenario.
+func DecodeFilter(data []byte) (model.Filter, error) {
+	var filter model.Filter
+	err := decodeStrict(data, &filter)
+	return filter, err
+}
+
+func decodeStrict(data []byte, value interface{}) error {
+	root := newSchemaGenerator().nodeFor(reflect.TypeOf(value))
+
+	d := &strictDecoder{data: data, decoder: json.NewDecoder(bytes.NewReader(data))}
+	d.decoder.UseNumber()
+	if err := d.decodeValue(root, false, ""); err != nil {
+		return d.syntaxError(err)
+	}
+	if _, err := d.decoder.Token(); err != io.EOF {
+		d.addError(d.decoder.InputOffset(), "", "unexpected data after the json value")
+	}
+	if len(d.errors) > 0 {
+		return d.errors
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			d.addError(typeError.Offset, "/"+strings.ReplaceAll(typeError.Field, ".", "/"), err.Error())
+			return d.errors
+		}
+		return err
+	}
+	return nil
+}
+
+type strictDecoder struct {
+	data    []byte
+	decoder *json.Decoder
+	errors  DecodeErrors
+}
+
+// decodeValue reads the next json value and checks it against node. It returns an error only for invalid json.
+func (d *strictDecoder) decodeValue(node *schemaNode, nullable bool, location string) error {
+	offset := d.tokenStart()
+	token, err := d.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token == nil {
+		if !nullable && node.kind != "" {
+			d.addError(offset, location, fmt.Sprintf("expected %s, found null", node.jsonKind()))
+		}
+		return nil
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' && (node.kind == kindObject || node.kind == kindMap) {
+			return d.decodeObject(node, offset, location)
+		}
+		if value == '[' && node.kind == kindArray {
+			return d.decodeArray(node, location)
+		}
+		if node.kind != "" {
+			d.addError(offset, location, fmt.Sprintf("expected %s, found %s", node.jsonKind(), kindOfDelim(value)))
+		}
+		return d.skipContainer()
+	case string:
+		if node.kind != kindString && node.kind != "" {
+			d.addError(offset, location, fmt.Sprintf("expected %s, found string", node.jsonKind()))
+		} else if len(node.enum) > 0 && !contains(node.enum, value) {
+			d.addError(offset, location, fmt.Sprintf("invalid value %q, expected one of %s", value, strings.Join(node.enum, ", ")))
+		}
+	case json.Number:
+		if node.kind == kindInteger {
+			if _, err := value.Int64(); err != nil {
+				d.addError(offset, location, fmt.Sprintf("expected integer, found %s", value))
+			}
+		} else if node.kind != kindNumber && node.kind != "" {
+			d.addError(offset, location, fmt.Sprintf("expected %s, found number", node.jsonKind()))
+		}
+	case bool:
+		if node.kind != kindBoolean && node.kind != "" {
+			d.addError(offset, location, fmt.Sprintf("expected %s, found boolean", node.jsonKind()))
+		}
+	}
+	return nil
+}
+
+func (d *strictDecoder) decodeObject(node *schemaNode, start int64, location string) error {
+	present := make(map[string]bool)
+	discriminator := ""
+
+	for d.decoder.More() {
+		keyOffset := d.tokenStart()
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		present[key] = true
+		propertyLocation := pointer(location, key)
+
+		if node.kind == kindMap {
+			if err := d.decodeValue(node.values, false, propertyLocation); err != nil {
+				return err
+			}
+			continue
+		}
+
+		property := node.property(key)
+		if property == nil {
+			d.addError(keyOffset, propertyLocation, fmt.Sprintf("unknown property %q", key))
+			if err := d.skipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if node.requiredWhen != nil && key == node.requiredWhen.property {
+			valueOffset := d.tokenStart()
+			if err := d.decodeValue(property.node, property.nullable, propertyLocation); err != nil {
+				return err
+			}
+			discriminator = d.discriminatorValue(valueOffset)
+			if !contains(node.requiredWhen.values, discriminator) {
+				d.addError(valueOffset, propertyLocation, fmt.Sprintf("invalid value %q, expected one of %s", discriminator, strings.Join(node.requiredWhen.values, ", ")))
+			}
+			continue
+		}
+
+		if err := d.decodeValue(property.node, property.nullable, propertyLocation); err != nil {
+			return err
+		}
+	}
+	if _, err := d.decoder.Token(); err != nil {
+		return err
+	}
+
+	required := node.required
+	if node.requiredWhen != nil {
+		required = append(append([]string{}, required...), node.requiredWhen.required[discriminator]...)
+	}
+	for _, name := range required {
+		if !present[name] {
+			d.addError(start, location, fmt.Sprintf("missing required property %q", name))
+		}
+	}
+	return nil
+}
+
+func (d *strictDecoder) decodeArray(node *schemaNode, location string) error {
+	for index := 0; d.decoder.More(); index++ {
+		if err := d.decodeValue(node.items, false, pointer(location, fmt.Sprintf("%d", index))); err != nil {
+			return err
+		}
+	}
+	_, err := d.decoder.Token()
+	return err
+}
+
+// discriminatorValue returns the string at offset, or an empty string if it is not a string
+func (d *strictDecoder) discriminatorValue(offset int64) string {
+	var value string
+	end := d.decoder.InputOffset()
+	if json.Unmarshal(d.data[offset:end], &value) != nil {
+		return ""
+	}
+	return value
+}
+
+func (d *strictDecoder) skipValue() error {
+	var skipped json.RawMessage
+	return d.decoder.Decode(&skipped)
+}
+
+// skipContainer skips the rest of an object or array whose opening delimiter has been read
+func (d *strictDecoder) skipContainer() error {
+	for depth := 1; depth > 0; {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// tokenStart returns the offset of the next token, skipping the white space and separators after the last one
+func (d *strictDecoder) tokenStart() int64 {
+	offset := d.decoder.InputOffset()
+	for offset < int64(len(d.data)) && strings.IndexByte(" \t\r\n,:", d.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func (d *strictDecoder) syntaxError(err error) error {
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		d.addError(syntaxError.Offset, "", err.Error())
+		return d.errors
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		d.addError(int64(len(d.data)), "", "unexpected end of json")
+		return d.errors
+	}
+	return err
+}
+
+func (d *strictDecoder) addError(offset int64, location string, message string) {
+	line, column := lineAndColumn(d.data, offset)
+	d.errors = append(d.errors, DecodeError{
+		ValidationIssue: ValidationIssue{Location: location, Message: message},
+		Offset:          offset,
+		Line:            line,
+		Column:          column,
+	})
+}
+
+func lineAndColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line, column := 1, 1
+	for _, c := range data[:offset] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+func kindOfDelim(delim json.Delim) string {
+	if delim == '[' {
+		return kindArray
+	}
+	return kindObject
+}
+
+// jsonKind is the json type of the values of node
+func (node *schemaNode) jsonKind() string {
+	if node.kind == kindMap {
+		return kindObject
+	}
+	return node.kind
+}
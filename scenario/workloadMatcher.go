@@ -0,0 +1,270 @@
+package scenario
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	zkLogger "github.com/zerok-ai/zk-utils-go/logs"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"sort"
+	"sync"
+)
+
+// WorkloadMatcher evaluates the workloads of all the scenarios against a span in one pass. Identical workloads are
+// evaluated once (they share the same model.WorkLoadUUID) and identical rule leaves are evaluated at most once per
+// span, even when they appear in different workloads.
+type WorkloadMatcher struct {
+	ruleEvaluator *evaluators.RuleEvaluator
+
+	mutex     sync.RWMutex
+	scenarios map[string]*model.Scenario
+	workloads map[uuid.UUID]*matcherWorkload
+
+	// programs holds the workloads compiled for each attribute store key
+	programs map[string]*workloadProgram
+}
+
+type matcherWorkload struct {
+	workload model.Workload
+
+	// ids are the keys the workload is stored against in the scenarios
+	ids []string
+}
+
+// workloadProgram is the set of workloads compiled for one attribute store key. The rule leaves of all the workloads
+// are in leaves, and the rule trees refer to them by index.
+type workloadProgram struct {
+	leaves    []*evaluators.CompiledRule
+	workloads []compiledWorkload
+}
+
+type compiledWorkload struct {
+	ids  []string
+	rule matchNode
+}
+
+type matchNode struct {
+	condition model.Condition
+	children  []matchNode
+
+	// leaf is the index of the rule leaf in workloadProgram.leaves, or -1 for groups
+	leaf int
+}
+
+const (
+	leafNotEvaluated int8 = iota
+	leafTrue
+	leafFalse
+	leafError
+)
+
+// NewWorkloadMatcher creates a matcher for the workloads of the scenarios, typically VersionedStore.GetAllValues()
+func NewWorkloadMatcher(ruleEvaluator *evaluators.RuleEvaluator, scenarios map[string]*model.Scenario) *WorkloadMatcher {
+	wm := &WorkloadMatcher{ruleEvaluator: ruleEvaluator}
+	wm.SetScenarios(scenarios)
+	return wm
+}
+
+// SetScenarios replaces the scenarios whose workloads are matched. It is called when the scenarios are refreshed.
+func (wm *WorkloadMatcher) SetScenarios(scenarios map[string]*model.Scenario) {
+	workloads := make(map[uuid.UUID]*matcherWorkload)
+	for _, scenario := range scenarios {
+		if scenario == nil || scenario.Workloads == nil {
+			continue
+		}
+		for workloadId, workload := range *scenario.Workloads {
+			workloadUUID := model.WorkLoadUUID(workload)
+			mw, ok := workloads[workloadUUID]
+			if !ok {
+				mw = &matcherWorkload{workload: workload}
+				workloads[workloadUUID] = mw
+			}
+			if !containsString(mw.ids, workloadId) {
+				mw.ids = append(mw.ids, workloadId)
+			}
+		}
+	}
+
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+	wm.scenarios = scenarios
+	wm.workloads = workloads
+	wm.programs = make(map[string]*workloadProgram)
+}
+
+// Match returns the ids of the workloads that the span matches, sorted. Only the workloads of the executor and
+// protocol in attrStoreKey are evaluated; workloads without a protocol or with the GENERAL protocol apply to all
+// protocols. Workloads that fail to evaluate are logged and treated as not matching.
+func (wm *WorkloadMatcher) Match(attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) []string {
+	program := wm.getProgram(attrStoreKey)
+
+	leafResults := make([]int8, len(program.leaves))
+	matchingIds := make([]string, 0)
+	for _, workload := range program.workloads {
+		ok, err := program.eval(workload.rule, leafResults, valueStore)
+		if err != nil {
+			zkLogger.Error(LogTag, "Error while evaluating workload ", workload.ids, err)
+			continue
+		}
+		if ok {
+			matchingIds = append(matchingIds, workload.ids...)
+		}
+	}
+
+	sort.Strings(matchingIds)
+	return matchingIds
+}
+
+// MatchScenarios returns the titles of the scenarios whose filters are satisfied by the workloads the span matches
+func (wm *WorkloadMatcher) MatchScenarios(attrStoreKey cache.AttribStoreKey, valueStore map[string]interface{}) ([]string, error) {
+	workloadIds := wm.Match(attrStoreKey, valueStore)
+
+	wm.mutex.RLock()
+	scenarios := wm.scenarios
+	wm.mutex.RUnlock()
+
+	return FindMatchingScenarios(workloadIds, scenarios)
+}
+
+func (wm *WorkloadMatcher) getProgram(attrStoreKey cache.AttribStoreKey) *workloadProgram {
+	wm.mutex.RLock()
+	program, ok := wm.programs[attrStoreKey.Value]
+	wm.mutex.RUnlock()
+	if ok {
+		return program
+	}
+
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+	if program, ok = wm.programs[attrStoreKey.Value]; ok {
+		return program
+	}
+	program = wm.compile(attrStoreKey)
+	wm.programs[attrStoreKey.Value] = program
+	return program
+}
+
+func (wm *WorkloadMatcher) compile(attrStoreKey cache.AttribStoreKey) *workloadProgram {
+	program := &workloadProgram{leaves: make([]*evaluators.CompiledRule, 0)}
+	leafIndex := make(map[string]int)
+
+	// compile in a fixed order so that the program is the same for the same scenarios
+	workloadUUIDs := make([]uuid.UUID, 0, len(wm.workloads))
+	for workloadUUID, mw := range wm.workloads {
+		if appliesTo(mw.workload, attrStoreKey) {
+			workloadUUIDs = append(workloadUUIDs, workloadUUID)
+		}
+	}
+	sort.Slice(workloadUUIDs, func(i, j int) bool {
+		return workloadUUIDs[i].String() < workloadUUIDs[j].String()
+	})
+
+	for _, workloadUUID := range workloadUUIDs {
+		mw := wm.workloads[workloadUUID]
+		node, err := wm.compileNode(mw.workload.Rule, attrStoreKey, program, leafIndex)
+		if err != nil {
+			zkLogger.Error(LogTag, "Error while compiling workload ", mw.ids, err)
+			continue
+		}
+		program.workloads = append(program.workloads, compiledWorkload{ids: mw.ids, rule: node})
+	}
+	return program
+}
+
+func (wm *WorkloadMatcher) compileNode(rule model.Rule, attrStoreKey cache.AttribStoreKey, program *workloadProgram, leafIndex map[string]int) (matchNode, error) {
+	if rule.Type == model.RULE_GROUP {
+		if rule.RuleGroup == nil || rule.Condition == nil {
+			return matchNode{}, fmt.Errorf("condition is nil")
+		}
+		node := matchNode{condition: *rule.Condition, leaf: -1, children: make([]matchNode, 0, len(rule.Rules))}
+		for _, childRule := range rule.Rules {
+			child, err := wm.compileNode(childRule, attrStoreKey, program, leafIndex)
+			if err != nil {
+				return matchNode{}, err
+			}
+			node.children = append(node.children, child)
+		}
+		return node, nil
+	}
+
+	key := leafKey(rule)
+	if index, ok := leafIndex[key]; ok {
+		return matchNode{leaf: index}, nil
+	}
+
+	compiledLeaf, err := wm.ruleEvaluator.CompileRule(rule, attrStoreKey)
+	if err != nil {
+		return matchNode{}, err
+	}
+	program.leaves = append(program.leaves, compiledLeaf)
+	leafIndex[key] = len(program.leaves) - 1
+	return matchNode{leaf: len(program.leaves) - 1}, nil
+}
+
+// eval evaluates the rule tree of a workload. Leaves are evaluated lazily and their results are kept in leafResults
+// so that other workloads with the same leaf reuse them.
+func (program *workloadProgram) eval(node matchNode, leafResults []int8, valueStore map[string]interface{}) (bool, error) {
+	if node.leaf < 0 {
+		return node.condition.Evaluate(len(node.children), func(index int) (bool, error) {
+			return program.eval(node.children[index], leafResults, valueStore)
+		})
+	}
+
+	switch leafResults[node.leaf] {
+	case leafTrue:
+		return true, nil
+	case leafFalse:
+		return false, nil
+	}
+
+	// errors are evaluated again so that the error can be reported for each workload
+	result, err := program.leaves[node.leaf].Eval(valueStore)
+	if err != nil {
+		leafResults[node.leaf] = leafError
+		return false, err
+	}
+	if result {
+		leafResults[node.leaf] = leafTrue
+	} else {
+		leafResults[node.leaf] = leafFalse
+	}
+	return result, nil
+}
+
+// appliesTo returns true if the workload's rules are written for the attributes of spans with attrStoreKey
+func appliesTo(workload model.Workload, attrStoreKey cache.AttribStoreKey) bool {
+	if workload.Executor != "" && string(workload.Executor) != attrStoreKey.Executor {
+		return false
+	}
+	return workload.Protocol == "" || workload.Protocol == model.ProtocolGeneral || string(workload.Protocol) == attrStoreKey.Protocol
+}
+
+// leafKey identifies rule leaves that evaluate to the same result for a span. Leaves which differ only in their match
+// options evaluate differently, so the options are part of the key.
+func leafKey(rule model.Rule) string {
+	if rule.RuleLeaf == nil {
+		return ""
+	}
+	options := ""
+	if rule.Options != nil {
+		options = rule.Options.String()
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", stringOrEmpty(rule.ID), stringOrEmpty(rule.Datatype), stringOrEmpty(rule.Operator), stringOrEmpty(rule.Value), options)
+}
+
+func stringOrEmpty[T ~string](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
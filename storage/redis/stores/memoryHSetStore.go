@@ -0,0 +1,98 @@
+package stores
+
+import (
+	"github.com/zerok-ai/zk-utils-go/ds"
+	"path"
+	"sync"
+)
+
+// MemoryHSetStore is a LocalCacheHSetStore which keeps all the hashes in memory instead of redis. It is meant for
+// tests and offline tools which have the store data at hand.
+type MemoryHSetStore struct {
+	mutex sync.RWMutex
+	data  map[string]map[string]string
+}
+
+// NewMemoryHSetStore creates a store with the hashes in data, by key. data is copied.
+func NewMemoryHSetStore(data map[string]map[string]string) *LocalCacheHSetStore {
+	store := &MemoryHSetStore{data: make(map[string]map[string]string, len(data))}
+	for key, value := range data {
+		store.data[key] = copyHash(value)
+	}
+
+	var localCacheHSetStore LocalCacheHSetStore = store
+	return &localCacheHSetStore
+}
+
+// NewMemoryExecutorAttrStore creates an ExecutorAttrStore with the attributes in data, by executor_version_protocol
+// key, the same as the keys in the redis db of the executor attributes
+func NewMemoryExecutorAttrStore(data map[string]map[string]string) *ExecutorAttrStore {
+	return (&ExecutorAttrStore{
+		localCacheHSetStore: *NewMemoryHSetStore(data),
+	}).initialize()
+}
+
+func (store *MemoryHSetStore) Close() {
+}
+
+// SetCache does nothing, as the data is always in memory
+func (store *MemoryHSetStore) SetCache(cache ds.Cache[map[string]string]) {
+}
+
+func (store *MemoryHSetStore) PutInLocalCache(key string, value *map[string]string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if value == nil {
+		delete(store.data, key)
+		return
+	}
+	store.data[key] = copyHash(*value)
+}
+
+func (store *MemoryHSetStore) Get(key string) (*map[string]string, bool) {
+	return store.GetFromLocalCache(key)
+}
+
+func (store *MemoryHSetStore) GetFromLocalCache(key string) (*map[string]string, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	value, ok := store.data[key]
+	if !ok {
+		return nil, false
+	}
+	return &value, true
+}
+
+// GetFromRedis returns an empty hash for missing keys, like HGETALL
+func (store *MemoryHSetStore) GetFromRedis(key string) (*map[string]string, error) {
+	value, ok := store.GetFromLocalCache(key)
+	if !ok {
+		value = &map[string]string{}
+	}
+	return value, nil
+}
+
+// GetAllKeysFromRedis returns the keys matching the glob pattern, like KEYS
+func (store *MemoryHSetStore) GetAllKeysFromRedis(pattern string) (*[]string, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	keys := make([]string, 0, len(store.data))
+	for key := range store.data {
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			keys = append(keys, key)
+		}
+	}
+	return &keys, nil
+}
+
+func copyHash(value map[string]string) map[string]string {
+	hash := make(map[string]string, len(value))
+	for field, fieldValue := range value {
+		hash[field] = fieldValue
+	}
+	return hash
+}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/proto/enrichedSpan"
+	"testing"
+)
+
+func TestGetValueStore(t *testing.T) {
+	// a span without events has no span_events
+	span := enrichedSpan.OtelEnrichedRawSpan{SpanAttributes: common.GenericMap{"status": 500}}
+	valueStore := span.GetValueStore()
+	assert.Equal(t, map[string]interface{}{"status": 500}, valueStore)
+
+	span.SpanEvents = []common.GenericMap{{"name": "exception"}}
+	valueStore = span.GetValueStore()
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "exception"}}, valueStore[enrichedSpan.SpanEventsKey])
+	_, ok := span.SpanAttributes[enrichedSpan.SpanEventsKey]
+	assert.False(t, ok)
+
+	// a span attribute named span_events is kept
+	span.SpanAttributes[enrichedSpan.SpanEventsKey] = "attribute"
+	valueStore = span.GetValueStore()
+	assert.Equal(t, "attribute", valueStore[enrichedSpan.SpanEventsKey])
+}
@@ -0,0 +1,53 @@
+package files
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/proto/enrichedSpan"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/test/files/helpers"
+	"testing"
+)
+
+func TestGroupByEvaluator(t *testing.T) {
+
+	scenarios := map[string]*model.Scenario{
+		"sc1": {Id: "sc1", GroupBy: []model.GroupBy{
+			{WorkloadId: "idA", Title: "req_path", Hash: "req_path"},
+			{WorkloadId: "idA", Title: "address", Hash: "status"},
+			{WorkloadId: "idB", Title: "req_method", Hash: "req_method"},
+		}},
+		"sc2": {Id: "sc2", GroupBy: []model.GroupBy{{WorkloadId: "idB", Title: "req_method", Hash: "req_method"}}},
+	}
+	dataStore := map[string]interface{}{
+		"req_path": "/api/users",
+		"status":   500,
+		"address":  map[string]interface{}{"city": "Bangalore"},
+	}
+
+	groupByEvaluator := helpers.GetGroupByEvaluator()
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	span := &enrichedSpan.OtelEnrichedRawSpan{WorkloadIdList: []string{"idA"}}
+	groupByEvaluator.FillGroupBy(span, scenarios, key, dataStore)
+
+	assert.Equal(t, common.GroupByMap{
+		"sc1": {
+			{WorkloadId: "idA", Title: "/api/users", Hash: "/api/users"},
+			{WorkloadId: "idA", Title: "{\"city\":\"Bangalore\"}", Hash: "500"},
+		},
+	}, span.GroupBy)
+
+	// the group key does not depend on the order of the values
+	groupByValues := span.GroupBy["sc1"]
+	reversed := common.GroupByValues{groupByValues[1], groupByValues[0]}
+	assert.Equal(t, evaluators.GroupKey(groupByValues), evaluators.GroupKey(reversed))
+
+	// values not found in the store are empty
+	groupByValues = groupByEvaluator.EvaluateGroupBy(*scenarios["sc2"], "idB", key, dataStore)
+	assert.Equal(t, common.GroupByValues{{WorkloadId: "idB", Title: "", Hash: ""}}, groupByValues)
+	assert.NotEqual(t, evaluators.GroupKey(span.GroupBy["sc1"]), evaluators.GroupKey(groupByValues))
+}
@@ -29,3 +29,16 @@ func LoadObjects[T comparable](schemaPath string, schemaObj *T, dataPath string,
 	}
 	return nil
 }
+
+func ValidateCompiled(t *testing.T, w model.Workload, dataStore map[string]interface{}, expected bool) {
+	ruleEvaluator := GetRuleEvaluator()
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	compiledRule, err := ruleEvaluator.CompileRule(w.Rule, key)
+	assert.NoError(t, err)
+
+	result, err := compiledRule.Eval(dataStore)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
@@ -21,6 +21,16 @@ func GetRuleEvaluator() *evaluators.RuleEvaluator {
 	return evaluators.NewRuleEvaluator(executorAttrDB, podDetailsStore)
 }
 
+func GetGroupByEvaluator() *evaluators.GroupByEvaluator {
+
+	configPath := "config/config.yaml"
+	sf := GetStoreFactory(configPath)
+	executorAttrDB := sf.GetExecutorAttrStore()
+	podDetailsStore := sf.GetPodDetailsStore()
+
+	return evaluators.NewGroupByEvaluator(executorAttrDB, podDetailsStore)
+}
+
 func GetStoreFactory(configPath string) *stores.StoreFactory {
 	var cfg config.AppConfigs
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
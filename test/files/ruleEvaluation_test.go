@@ -1,13 +1,17 @@
 package files
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	zklogger "github.com/zerok-ai/zk-utils-go/logs"
 	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators"
 	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
 	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
 	"github.com/zerok-ai/zk-utils-go/test/files/helpers"
+	"sync"
 	"testing"
 )
 
@@ -51,6 +55,16 @@ func TestRuleEvaluationBooleanNotEqual(t *testing.T) {
 	helpers.Validate(t, w, dataStore, true)
 }
 
+func TestRuleEvaluationNegatedGroups(t *testing.T) {
+
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("ruleEvaluation/not/schema.json", &w, "ruleEvaluation/not/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
 func TestRuleEvaluationString(t *testing.T) {
 
 	var dataStore map[string]interface{}
@@ -97,8 +111,236 @@ func TestRuleEvaluationExistsOperator(t *testing.T) {
 	helpers.Validate(t, w, dataStore, true)
 }
 
+func TestRuleEvaluationDuration(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/duration/schema.json", &w, "./ruleEvaluation/duration/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationTimestamp(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/timestamp/schema.json", &w, "./ruleEvaluation/timestamp/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationBytes(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/bytes/schema.json", &w, "./ruleEvaluation/bytes/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationKeyValue(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/keyvalue/schema.json", &w, "./ruleEvaluation/keyvalue/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationCollection(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/collection/schema.json", &w, "./ruleEvaluation/collection/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationStringOptions(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/stringoptions/schema.json", &w, "./ruleEvaluation/stringoptions/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationAggregate(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/aggregate/schema.json", &w, "./ruleEvaluation/aggregate/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationStringFunctions(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/stringfunctions/schema.json", &w, "./ruleEvaluation/stringfunctions/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationHttpHeaders(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/httpheaders/schema.json", &w, "./ruleEvaluation/httpheaders/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationIPAddress(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/ipaddress/schema.json", &w, "./ruleEvaluation/ipaddress/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	helpers.Validate(t, w, dataStore, true)
+}
+
+func TestRuleEvaluationWorkloadIdentifier(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("./ruleEvaluation/workloadidentifier/schema.json", &w, "./ruleEvaluation/workloadidentifier/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	var pods map[string]map[string]string
+	err = helpers.LoadFile("./ruleEvaluation/workloadidentifier/pods.json", &pods, false)
+	assert.NoError(t, err)
+
+	ruleEvaluator := evaluators.NewRuleEvaluator(stores.NewMemoryExecutorAttrStore(nil), stores.NewMemoryHSetStore(pods))
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	result, err := ruleEvaluator.EvalRule(w.Rule, key, dataStore)
+	assert.NoError(t, err)
+	assert.True(t, result)
+
+	compiledRule, err := ruleEvaluator.CompileRule(w.Rule, key)
+	assert.NoError(t, err)
+	result, err = compiledRule.Eval(dataStore)
+	assert.NoError(t, err)
+	assert.True(t, result)
+
+	// the payments pod has no service name, so it is identified by its workload name
+	checkout := model.Rule{}
+	err = json.Unmarshal([]byte(`{"type": "rule", "id": "source_ip", "datatype": "workload-identifier", "operator": "equal", "value": "shop/checkout"}`), &checkout)
+	assert.NoError(t, err)
+	result, err = ruleEvaluator.EvalRule(checkout, key, dataStore)
+	assert.NoError(t, err)
+	assert.False(t, result)
+}
+
+func TestCompiledRuleEvaluation(t *testing.T) {
+	fixtures := []struct {
+		path     string
+		expected bool
+	}{
+		{"ruleEvaluation/string", true},
+		{"ruleEvaluation/float", true},
+		{"ruleEvaluation/integer", true},
+		{"ruleEvaluation/exists", true},
+		{"ruleEvaluation/bool/and", true},
+		{"ruleEvaluation/bool/or", false},
+		{"ruleEvaluation/duration", true},
+		{"ruleEvaluation/timestamp", true},
+		{"ruleEvaluation/bytes", true},
+		{"ruleEvaluation/keyvalue", true},
+		{"ruleEvaluation/collection", true},
+		{"ruleEvaluation/stringoptions", true},
+		{"ruleEvaluation/aggregate", true},
+		{"ruleEvaluation/stringfunctions", true},
+		{"ruleEvaluation/httpheaders", true},
+		{"ruleEvaluation/ipaddress", true},
+	}
+
+	for _, fixture := range fixtures {
+		var dataStore map[string]interface{}
+		var w model.Workload
+		err := helpers.LoadObjects(fixture.path+"/schema.json", &w, fixture.path+"/data.json", &dataStore)
+		assert.NoError(t, err)
+
+		helpers.ValidateCompiled(t, w, dataStore, fixture.expected)
+	}
+}
+
+func TestRuleEvaluationWithTrace(t *testing.T) {
+	var dataStore map[string]interface{}
+	var w model.Workload
+	err := helpers.LoadObjects("ruleEvaluation/bool/or/schema.json", &w, "ruleEvaluation/bool/or/data.json", &dataStore)
+	assert.NoError(t, err)
+
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	result, trace, err := helpers.GetRuleEvaluator().EvalRuleWithTrace(w.Rule, key, dataStore)
+	assert.NoError(t, err)
+	assert.Equal(t, false, result)
+	assert.Equal(t, result, trace.Result)
+	assert.Equal(t, len(w.Rule.Rules), len(trace.Rules))
+	for _, childTrace := range trace.Rules {
+		assert.False(t, childTrace.Result)
+		if childTrace.Type == model.RULE {
+			assert.NotEmpty(t, childTrace.ID)
+			assert.NotEmpty(t, childTrace.ResolvedPath)
+		} else {
+			assert.NotEmpty(t, childTrace.Rules)
+		}
+	}
+}
+
+// countedCalls counts the executions of #counted(), which returns its input. The function is registered once, as the
+// registry is shared by all the tests.
+var (
+	countedCalls    = 0
+	registerCounted sync.Once
+)
+
+type counted struct{}
+
+func (fn counted) Execute(valueAtObject interface{}) (interface{}, bool) {
+	countedCalls++
+	return valueAtObject, true
+}
+
+func (fn counted) GetName() string {
+	return "counted"
+}
+
+func TestRuleEvaluationTraceResolvedPath(t *testing.T) {
+	registerCounted.Do(func() {
+		err := functions.RegisterFunction("counted", func(args []string, ctx functions.FunctionContext) functions.Function {
+			return counted{}
+		})
+		assert.NoError(t, err)
+	})
+	countedCalls = 0
+
+	var rule model.Rule
+	err := json.Unmarshal([]byte(`{"type": "rule", "id": "method#counted()#toLowerCase()", "datatype": "string",
+		"operator": "equal", "value": "get"}`), &rule)
+	assert.NoError(t, err)
+
+	attrStore := stores.NewMemoryExecutorAttrStore(map[string]map[string]string{"OTEL_1.21.0_HTTP": {"method": "req_method"}})
+	ruleEvaluator := evaluators.NewRuleEvaluator(attrStore, stores.NewMemoryHSetStore(nil))
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	result, trace, err := ruleEvaluator.EvalRuleWithTrace(rule, key, map[string]interface{}{"req_method": "GET"})
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, "req_method#counted()#toLowerCase()", trace.ResolvedPath)
+	assert.Equal(t, "get", trace.Value)
+
+	// the rule is checked on the traced value without evaluating the path again
+	assert.Equal(t, 1, countedCalls)
+}
+
 func TestFunction(t *testing.T) {
-	input := "path123.#func1(param1).#func2().#func3(param31, param32)"
+	input := "path123.#substring(1).#toUpperCase().#split(\",\")"
 
 	configPath := "config/config.yaml"
 	sf := helpers.GetStoreFactory(configPath)
@@ -109,7 +351,11 @@ func TestFunction(t *testing.T) {
 
 	functionArr := ff.GetPathAndFunctions(input, &key)
 
-	assert.Greater(t, len(functionArr), 0)
+	names := make([]string, 0, len(functionArr))
+	for _, fn := range functionArr {
+		names = append(names, fn.GetName())
+	}
+	assert.Equal(t, []string{functions.NoName, "substring", "toUpperCase", "split"}, names)
 }
 
 func TestValueFromStore(t *testing.T) {
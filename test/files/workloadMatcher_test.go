@@ -0,0 +1,83 @@
+package files
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/scenario"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+	"github.com/zerok-ai/zk-utils-go/test/files/helpers"
+	"testing"
+)
+
+func TestWorkloadMatcher(t *testing.T) {
+
+	var scenarios map[string]*model.Scenario
+	err := helpers.LoadFile("workloadMatcher/scenarios.json", &scenarios, false)
+	assert.NoError(t, err)
+
+	matcher := scenario.NewWorkloadMatcher(helpers.GetRuleEvaluator(), scenarios)
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	postException := map[string]interface{}{"req_method": "POST", "req_path": "/api/exception", "resp_status": 200}
+	assert.Equal(t, []string{"post"}, matcher.Match(key, postException))
+
+	serverError := map[string]interface{}{"req_method": "POST", "req_path": "/api/users", "resp_status": 503}
+	assert.Equal(t, []string{"errors"}, matcher.Match(key, serverError))
+
+	getRequest := map[string]interface{}{"req_method": "GET", "req_path": "/api/exception", "resp_status": 503}
+	assert.Empty(t, matcher.Match(key, getRequest))
+
+	scenarioTitles, err := matcher.MatchScenarios(key, postException)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"post exceptions", "post or server errors"}, scenarioTitles)
+
+	scenarioTitles, err = matcher.MatchScenarios(key, serverError)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"post or server errors"}, scenarioTitles)
+
+	// the mysql workload is only evaluated for ebpf mysql spans
+	mysqlKey, err := cache.ParseKey("EBPF_0.1.0-alpha_MYSQL")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, matcher.Match(mysqlKey, postException))
+
+	// scenarios are replaced when they are refreshed
+	delete(scenarios, "sc2")
+	matcher.SetScenarios(scenarios)
+	assert.Empty(t, matcher.Match(key, serverError))
+}
+
+func TestWorkloadMatcherMatchOptions(t *testing.T) {
+	var scenarios map[string]*model.Scenario
+	err := json.Unmarshal([]byte(`{"sc1": {
+		"scenario_title": "get requests", "scenario_type": "user", "version": "1", "scenario_id": "sc1", "enabled": true,
+		"workloads": {
+			"exact": {"executor": "OTEL", "service": "*/*", "trace_role": "server", "protocol": "HTTP",
+				"rule": {"type": "rule_group", "condition": "AND", "rules": [
+					{"type": "rule", "id": "req_method", "datatype": "string", "operator": "equal", "value": "GET"}]}},
+			"anycase": {"executor": "OTEL", "service": "*/*", "trace_role": "server", "protocol": "HTTP",
+				"rule": {"type": "rule_group", "condition": "AND", "rules": [
+					{"type": "rule", "id": "req_method", "datatype": "string", "operator": "equal", "value": "GET",
+						"options": {"ignore_case": true}}]}},
+			"nooptions": {"executor": "OTEL", "service": "*/*", "trace_role": "server", "protocol": "HTTP",
+				"rule": {"type": "rule_group", "condition": "AND", "rules": [
+					{"type": "rule", "id": "req_method", "datatype": "string", "operator": "equal", "value": "GET",
+						"options": {}}]}}
+		},
+		"filter": {"type": "workload", "condition": "OR", "workload_ids": ["exact", "anycase", "nooptions"]},
+		"group_by": [], "rate_limit": []
+	}}`), &scenarios)
+	assert.NoError(t, err)
+
+	ruleEvaluator := evaluators.NewRuleEvaluator(stores.NewMemoryExecutorAttrStore(nil), stores.NewMemoryHSetStore(nil))
+	matcher := scenario.NewWorkloadMatcher(ruleEvaluator, scenarios)
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	// leaves differing only in their match options are not shared, and empty options are the same as no options
+	assert.Equal(t, []string{"anycase"}, matcher.Match(key, map[string]interface{}{"req_method": "get"}))
+	assert.ElementsMatch(t, []string{"exact", "anycase", "nooptions"}, matcher.Match(key, map[string]interface{}{"req_method": "GET"}))
+}
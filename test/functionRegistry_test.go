@@ -0,0 +1,110 @@
+package test
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+)
+
+// repeat is a custom function returning its input string repeated a number of times, with an optional separator
+type repeat struct {
+	count     int
+	separator string
+}
+
+func (fn repeat) Execute(valueAtObject interface{}) (interface{}, bool) {
+	str, ok := valueAtObject.(string)
+	if !ok {
+		return nil, false
+	}
+	return strings.Repeat(str+fn.separator, fn.count), true
+}
+
+func (fn repeat) GetName() string {
+	return "repeat"
+}
+
+var registerRepeat sync.Once
+
+func TestRegisterFunction(t *testing.T) {
+	newRepeat := func(args []string, ctx functions.FunctionContext) functions.Function {
+		fn := repeat{}
+		fn.count, _ = strconv.Atoi(args[0])
+		if len(args) > 1 {
+			fn.separator = args[1]
+		}
+		return fn
+	}
+	intParam := functions.Param{Name: "count", Type: functions.ArgInt}
+	separatorParam := functions.Param{Name: "separator", Type: functions.ArgString, Optional: true}
+
+	// the registry is shared by all the tests, so repeat is registered once when the tests run more than once
+	registerRepeat.Do(func() {
+		assert.NoError(t, functions.RegisterFunction("repeat", newRepeat, intParam, separatorParam))
+	})
+
+	// names are registered once, and the built-in functions use the same registry
+	assert.Error(t, functions.RegisterFunction("repeat", newRepeat))
+	assert.Error(t, functions.RegisterFunction("toLowerCase", newRepeat))
+	assert.Error(t, functions.RegisterFunction("bad name", newRepeat))
+	assert.Error(t, functions.RegisterFunction("misordered", newRepeat, separatorParam, intParam))
+	assert.Error(t, functions.RegisterFunction("noConstructor", nil))
+
+	// arity and argument types are checked when a path is validated
+	assert.NoError(t, functions.ValidatePath("method#repeat(2)"))
+	assert.NoError(t, functions.ValidatePath("method#repeat(2, -)#toLowerCase()"))
+	assert.ErrorContains(t, functions.ValidatePath("method#repeat()"), "takes arguments (count, separator?)")
+	assert.ErrorContains(t, functions.ValidatePath("method#repeat(two)"), "must be of type int")
+	assert.ErrorContains(t, functions.ValidatePath("method#repeat(2, -, +)"), "found 3")
+	assert.ErrorContains(t, functions.ValidatePath("method#toLowerCase(x)"), "takes no arguments")
+	assert.ErrorContains(t, functions.ValidatePath("req_body#jsonExtract()"), "takes arguments (path)")
+
+	ff := functions.NewFunctionFactory(stores.NewMemoryHSetStore(nil), stores.NewMemoryExecutorAttrStore(nil))
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	value, ok := ff.CompilePath("method#repeat(2, -)#toUpperCase()", &key).Evaluate(map[string]interface{}{"method": "get"})
+	assert.True(t, ok)
+	assert.Equal(t, "GET-GET-", value)
+
+	// a call which cannot be created leaves the path without a value
+	valueStore := map[string]interface{}{"req_path": "/API/users"}
+	for _, path := range []string{`req_path#regexExtract("(")`, `req_path#substring(abc)#toLowerCase()`, `req_path#nosuchfn()`} {
+		assert.Empty(t, ff.GetPathAndFunctions(path, &key), path)
+		_, ok = ff.EvaluateString(path, valueStore, &key)
+		assert.False(t, ok, path)
+		_, ok = ff.CompilePath(path, &key).Evaluate(valueStore)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	ff := functions.NewFunctionFactory(stores.NewMemoryHSetStore(nil), stores.NewMemoryExecutorAttrStore(nil))
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	valueStore := map[string]interface{}{"latencies": []interface{}{40, 10, 30, 20}, "empty": []interface{}{}}
+	for path, expected := range map[string]float64{
+		"latencies#percentile(0)":   10,
+		"latencies#percentile(50)":  25,
+		"latencies#percentile(75)":  32.5,
+		"latencies#percentile(100)": 40,
+	} {
+		value, ok := ff.EvaluateString(path, valueStore, &key)
+		assert.True(t, ok, path)
+		assert.InDelta(t, expected, value, 1e-9, path)
+	}
+
+	for _, path := range []string{"latencies#percentile(101)", "empty#percentile(50)"} {
+		_, ok := ff.EvaluateString(path, valueStore, &key)
+		assert.False(t, ok, path)
+	}
+	assert.ErrorContains(t, functions.ValidatePath("latencies#percentile(p90)"), "percentile")
+	assert.Error(t, functions.ValidatePath("latencies#percentile()"))
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+)
+
+func TestParsePath(t *testing.T) {
+	path, err := functions.ParsePath(`req_body#jsonExtract("user.name, full")#toLowerCase().first`)
+	assert.NoError(t, err)
+	assert.Equal(t, []functions.PathElement{
+		{Attribute: "req_body"},
+		{Call: &functions.Call{Name: "jsonExtract", Args: []functions.Arg{{Value: "user.name, full", Quoted: true}}}},
+		{Call: &functions.Call{Name: "toLowerCase", Args: []functions.Arg{}}},
+		{Attribute: "first"},
+	}, path.Elements)
+
+	// quoted jmespath keys keep their quotes, and can hold `#`, `.` and brackets
+	path, err = functions.ParsePath(`req_headers."x-tenant#id".value#len()`)
+	assert.NoError(t, err)
+	assert.Equal(t, `req_headers."x-tenant#id".value`, path.Elements[0].Attribute)
+	assert.Equal(t, "len", path.Elements[1].Call.Name)
+
+	// nested calls, brackets and quotes stay in one unquoted argument, and escapes are removed from quoted ones
+	path, err = functions.ParsePath(`body#count(tags[?a=='x,y'] == "(b)")#jsonExtract(items#count(name == 'a)b'), 'it\'s', "\d+\\")`)
+	assert.NoError(t, err)
+	assert.Equal(t, []functions.Arg{{Value: `tags[?a=='x,y'] == "(b)"`}}, path.Elements[1].Call.Args)
+	assert.Equal(t, []functions.Arg{
+		{Value: `items#count(name == 'a)b')`},
+		{Value: `it's`, Quoted: true},
+		{Value: `\d+\`, Quoted: true},
+	}, path.Elements[2].Call.Args)
+
+	// a `.` before a function separates it from the attribute
+	path, err = functions.ParsePath(`a.#f()`)
+	assert.NoError(t, err)
+	assert.Equal(t, []functions.PathElement{{Attribute: "a"}, {Call: &functions.Call{Name: "f", Args: []functions.Arg{}}}}, path.Elements)
+	path, err = functions.ParsePath(`b.c.#f().d.#g(x)`)
+	assert.NoError(t, err)
+	assert.Equal(t, "b.c", path.Elements[0].Attribute)
+	assert.Equal(t, "d", path.Elements[2].Attribute)
+	assert.Equal(t, `b.c#f().d#g(x)`, path.String())
+
+	// paths are cached
+	cached, err := functions.ParsePath(`req_headers."x-tenant#id".value#len()`)
+	assert.NoError(t, err)
+	same, _ := functions.ParsePath(`req_headers."x-tenant#id".value#len()`)
+	assert.Same(t, cached, same)
+
+	for _, input := range []string{
+		`a#f()`,
+		`a#f(x, "y")#g().b.c`,
+		`a#f("it's \"quoted\" \\ here", x#g(1, 2))`,
+		`"a#b"#f('(')`,
+	} {
+		path, err := functions.ParsePath(input)
+		assert.NoError(t, err, input)
+		printed := path.String()
+		reparsed, err := functions.ParsePath(printed)
+		assert.NoError(t, err, printed)
+		assert.Equal(t, path.Elements, reparsed.Elements, input)
+		assert.Equal(t, printed, reparsed.String())
+	}
+	path, _ = functions.ParsePath(`a#f( x ,'y' )`)
+	assert.Equal(t, `a#f(x, "y")`, path.String())
+
+	for input, message := range map[string]string{
+		`a#f(x`:          "function f is missing `)`",
+		`a#f`:            "function f is missing `(` at position 3",
+		`a#(x)`:          "function name missing at position 1",
+		`a#f(x,)`:        "missing argument of function f at position 6",
+		`a#f(,x)`:        "missing argument of function f at position 4",
+		`a#f("x" y)`:     `unexpected "y" at position 8 in the arguments of function f`,
+		`a#f("x)`:        "unterminated \" at position 4",
+		`a#f(x])`:        "unbalanced `]` at position 5",
+		`a#f()b`:         `unexpected "b" at position 5 after function f`,
+		`a#f().`:         "expected an attribute or a function after `.` at position 6",
+		`."a`:            "unexpected `.` at position 0",
+		`a#f(g(x), "y)`:  "unterminated \" at position 10",
+		`a."b#f(x)`:      "unterminated \" at position 2",
+		`a#f(x)#g(y)#h(`: "function h is missing `)`",
+	} {
+		_, err := functions.ParsePath(input)
+		assert.ErrorContains(t, err, message, input)
+	}
+}
+
+func TestPathWithQuotedArguments(t *testing.T) {
+	assert.NoError(t, functions.ValidatePath(`req_body#count("name == exception")`))
+	assert.NoError(t, functions.ValidatePath(`req_body#jsonExtract(user#toLowerCase())`))
+	assert.ErrorContains(t, functions.ValidatePath(`req_body#jsonExtract(user#unknown())`), "unknown function unknown in user#unknown()")
+	assert.ErrorContains(t, functions.ValidatePath(`req_body#len(`), "function len is missing `)` in req_body#len(")
+
+	ff := functions.NewFunctionFactory(stores.NewMemoryHSetStore(nil), stores.NewMemoryExecutorAttrStore(nil))
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	valueStore := map[string]interface{}{
+		"headers": map[string]interface{}{"x-tenant.id": "Acme"},
+		"events": []interface{}{
+			map[string]interface{}{"name": "a, b"},
+			map[string]interface{}{"name": "a (b)"},
+			map[string]interface{}{"name": "c"},
+		},
+	}
+
+	value, ok := ff.CompilePath(`headers."x-tenant.id"#toLowerCase()`, &key).Evaluate(valueStore)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", value)
+
+	value, ok = ff.EvaluateString(`user.name.#toLowerCase()`, map[string]interface{}{"user": map[string]interface{}{"name": "Xy"}}, &key)
+	assert.True(t, ok)
+	assert.Equal(t, "xy", value)
+
+	value, ok = ff.EvaluateString(`events#count(name == "a, b")`, valueStore, &key)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	value, ok = ff.CompilePath(`events#count(name != 'a (b)')`, &key).Evaluate(valueStore)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/ratelimit"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	scenarios := map[string]*model.Scenario{
+		"sc1": {Id: "sc1", Version: "1", RateLimit: []model.RateLimit{
+			{BucketMaxSize: 2, BucketRefillSize: 1, TickDuration: "1h"},
+		}},
+		"sc2": {Id: "sc2", Version: "1"},
+	}
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	rateLimiter := ratelimit.NewRateLimiterWithClock(scenarios, func() time.Time { return now })
+	defer rateLimiter.Close()
+
+	// each group key has its own bucket
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.True(t, rateLimiter.Allow("sc1", "groupB"))
+
+	// scenarios without rate limits are not limited
+	for i := 0; i < 5; i++ {
+		assert.True(t, rateLimiter.Allow("sc2", "groupA"))
+		assert.True(t, rateLimiter.Allow("unknown", "groupA"))
+	}
+
+	// refreshing with the same version keeps the buckets
+	rateLimiter.Refresh(scenarios)
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+
+	// a new version resets the buckets and applies the new limits
+	scenarios["sc1"] = &model.Scenario{Id: "sc1", Version: "2", RateLimit: []model.RateLimit{
+		{BucketMaxSize: 1, BucketRefillSize: 1, TickDuration: "50ms"},
+		{BucketMaxSize: 3, BucketRefillSize: 3, TickDuration: "1h"},
+	}}
+	rateLimiter.Refresh(scenarios)
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+
+	// the first bucket is refilled on every tick till the second bucket runs out
+	now = now.Add(50 * time.Millisecond)
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	now = now.Add(120 * time.Millisecond)
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	now = now.Add(50 * time.Millisecond)
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+
+	// the second bucket is refilled after its tick, counted from the refresh
+	now = now.Add(time.Hour)
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+}
+
+func TestRateLimiterScenarioScope(t *testing.T) {
+	scenarios := map[string]*model.Scenario{
+		"sc1": {Id: "sc1", Version: "1", RateLimit: []model.RateLimit{
+			{BucketMaxSize: 2, BucketRefillSize: 2, TickDuration: "1m"},
+			{BucketMaxSize: 3, BucketRefillSize: 1, TickDuration: "1m", Scope: model.RateLimitPerScenario},
+		}},
+	}
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	rateLimiter := ratelimit.NewRateLimiterWithClock(scenarios, func() time.Time { return now })
+	defer rateLimiter.Close()
+
+	// the scenario bucket is shared by the group keys
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.True(t, rateLimiter.Allow("sc1", "groupB"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupC"))
+
+	// Sync refills the scenario bucket by one token a tick
+	now = now.Add(time.Minute)
+	rateLimiter.Sync()
+	assert.True(t, rateLimiter.Allow("sc1", "groupC"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+}
+
+// scenarioStore is a ScenarioStore holding the scenarios in memory
+type scenarioStore struct {
+	mutex     sync.Mutex
+	scenarios map[string]*model.Scenario
+}
+
+func (store *scenarioStore) GetAllValues() map[string]*model.Scenario {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.scenarios
+}
+
+func (store *scenarioStore) set(scenarios map[string]*model.Scenario) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.scenarios = scenarios
+}
+
+func TestRateLimiterForStore(t *testing.T) {
+	store := &scenarioStore{scenarios: map[string]*model.Scenario{
+		"sc1": {Id: "sc1", Version: "1", RateLimit: []model.RateLimit{{BucketMaxSize: 1, BucketRefillSize: 1, TickDuration: "1h"}}},
+	}}
+	rateLimiter := ratelimit.NewRateLimiterForStore(store, time.Hour)
+	defer rateLimiter.Close()
+
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+
+	// a new version in the store replaces the buckets on sync
+	store.set(map[string]*model.Scenario{
+		"sc1": {Id: "sc1", Version: "2", RateLimit: []model.RateLimit{{BucketMaxSize: 2, BucketRefillSize: 1, TickDuration: "1h"}}},
+	})
+	rateLimiter.Sync()
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+	assert.False(t, rateLimiter.Allow("sc1", "groupA"))
+
+	// scenarios removed from the store are not limited
+	store.set(map[string]*model.Scenario{})
+	rateLimiter.Sync()
+	assert.True(t, rateLimiter.Allow("sc1", "groupA"))
+}
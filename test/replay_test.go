@@ -0,0 +1,107 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/common"
+	protoSpan "github.com/zerok-ai/zk-utils-go/proto"
+	"github.com/zerok-ai/zk-utils-go/proto/enrichedSpan"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/replay"
+	otlpTrace "go.opentelemetry.io/proto/otlp/trace/v1"
+	"testing"
+)
+
+func newReplayer(t *testing.T) *replay.Replayer {
+	var scenarios map[string]*model.Scenario
+	err := json.Unmarshal(common.GetBytesFromFile("files/workloadMatcher/scenarios.json"), &scenarios)
+	assert.NoError(t, err)
+
+	var attributes map[string]map[string]string
+	err = json.Unmarshal(common.GetBytesFromFile("files/replay/attributes.json"), &attributes)
+	assert.NoError(t, err)
+
+	attrStoreKey, err := replay.FixedAttrStoreKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	replayer, err := replay.NewReplayer(replay.Config{
+		Scenarios:         scenarios,
+		Attributes:        attributes,
+		AttrStoreKey:      attrStoreKey,
+		MaxSampleTraceIds: 1,
+	})
+	assert.NoError(t, err)
+	return replayer
+}
+
+func recordedSpan(traceId byte, spanId byte, method string, target string, status int64) *protoSpan.OtelEnrichedRawSpanForProto {
+	span := enrichedSpan.OtelEnrichedRawSpan{
+		Span: &otlpTrace.Span{
+			TraceId: bytes.Repeat([]byte{traceId}, 16),
+			SpanId:  bytes.Repeat([]byte{spanId}, 8),
+		},
+		SpanAttributes: common.GenericMap{"method": method, "target": target, "status": status},
+		// recorded workload ids are evaluated again
+		WorkloadIdList: []string{"errors"},
+	}
+	return span.GetProtoEnrichedSpan()
+}
+
+func TestReplayEnrichedSpans(t *testing.T) {
+	spans := []*protoSpan.OtelEnrichedRawSpanForProto{
+		recordedSpan(1, 1, "POST", "/api/exception", 200),
+		recordedSpan(1, 2, "POST", "/api/users", 503),
+		recordedSpan(2, 3, "POST", "/api/users", 500),
+		recordedSpan(3, 4, "GET", "/api/exception", 503),
+	}
+	recording := &bytes.Buffer{}
+	assert.NoError(t, replay.WriteMessages(recording, spans...))
+
+	replayer := newReplayer(t)
+	assert.NoError(t, replayer.Read(recording, replay.FormatEnrichedSpans))
+
+	trace1 := "01010101010101010101010101010101"
+	assert.Equal(t, replay.Report{
+		Spans:  4,
+		Traces: 3,
+		Workloads: []replay.WorkloadReport{
+			{WorkloadId: "errors", Spans: 2, Traces: 2, SampleTraceIds: []string{trace1}},
+			{WorkloadId: "post", Spans: 1, Traces: 1, SampleTraceIds: []string{trace1}},
+		},
+		Scenarios: []replay.ScenarioReport{
+			{ScenarioId: "sc1", Title: "post exceptions", Traces: 1, SampleTraceIds: []string{trace1}},
+			{ScenarioId: "sc2", Title: "post or server errors", Traces: 2, SampleTraceIds: []string{trace1}},
+			{ScenarioId: "sc3", Title: "mysql queries", Traces: 0, SampleTraceIds: []string{}},
+		},
+	}, replayer.Report())
+}
+
+func TestReplayBadgerResponseList(t *testing.T) {
+	responseList := &protoSpan.BadgerResponseList{
+		ResponseList: []*protoSpan.BadgerResponse{
+			{Key: "1", Value: recordedSpan(1, 1, "POST", "/api/exception", 200)},
+			{Key: "2", Value: recordedSpan(2, 2, "GET", "/api/users", 200)},
+		},
+	}
+	recording := &bytes.Buffer{}
+	assert.NoError(t, replay.WriteMessages(recording, responseList, responseList))
+
+	replayer := newReplayer(t)
+	assert.NoError(t, replayer.Read(recording, replay.FormatBadgerResponseList))
+
+	report := replayer.Report()
+	assert.Equal(t, 4, report.Spans)
+	assert.Equal(t, 2, report.Traces)
+	assert.Equal(t, []replay.WorkloadReport{
+		{WorkloadId: "post", Spans: 2, Traces: 1, SampleTraceIds: []string{"01010101010101010101010101010101"}},
+	}, report.Workloads)
+	assert.Equal(t, 1, report.Scenarios[0].Traces)
+	assert.Equal(t, 1, report.Scenarios[1].Traces)
+
+	// a truncated recording is an error
+	recording.Reset()
+	assert.NoError(t, replay.WriteMessages(recording, responseList))
+	truncated := bytes.NewReader(recording.Bytes()[:recording.Len()-3])
+	assert.Error(t, newReplayer(t).Read(truncated, replay.FormatBadgerResponseList))
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/scenario/dsl"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := dsl.ParseRule(`req_method == "POST" AND (req_path ends_with "/exception" OR status between 500,599)`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, model.RULE_GROUP, rule.Type)
+	assert.Equal(t, model.AND, *rule.Condition)
+	assert.Equal(t, 2, len(rule.Rules))
+	assert.Equal(t, "req_method", *rule.Rules[0].ID)
+	assert.Equal(t, model.OperatorTypes("equal"), *rule.Rules[0].Operator)
+	assert.Equal(t, model.ValueTypes("POST"), *rule.Rules[0].Value)
+	assert.Equal(t, model.DataType("string"), *rule.Rules[0].Datatype)
+
+	orGroup := rule.Rules[1]
+	assert.Equal(t, model.OR, *orGroup.Condition)
+	assert.Equal(t, model.OperatorTypes("between"), *orGroup.Rules[1].Operator)
+	assert.Equal(t, model.ValueTypes("500,599"), *orGroup.Rules[1].Value)
+	assert.Equal(t, model.DataType("integer"), *orGroup.Rules[1].Datatype)
+
+	rule, err = dsl.ParseRule(`NOT(req_method == "POST") AND NOR(status == 500, status == 503)`)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NOT, *rule.Rules[0].Condition)
+	assert.Equal(t, 1, len(rule.Rules[0].Rules))
+	assert.Equal(t, model.NOR, *rule.Rules[1].Condition)
+	assert.Equal(t, 2, len(rule.Rules[1].Rules))
+
+	// quoted arguments of functions can hold brackets
+	rule, err = dsl.ParseRule(`span_events#count(name == ")") > 0 AND status == 500`)
+	assert.NoError(t, err)
+	assert.Equal(t, `span_events#count(name == ")")`, *rule.Rules[0].ID)
+
+	_, err = dsl.ParseRule(`req_method == "POST" AND`)
+	assert.Error(t, err)
+	_, err = dsl.ParseRule(`req_method "POST"`)
+	assert.Error(t, err)
+}
+
+func TestFormatRuleRoundTrip(t *testing.T) {
+	leaves := []struct {
+		datatype string
+		operator string
+		value    string
+	}{
+		{"string", "exists", ""},
+		{"integer", "not_exists", ""},
+		{"string", "matches", ".*rover.*"},
+		{"string", "does_not_match", `^"quoted" \d+$`},
+		{"string", "equal", "POST"},
+		{"string", "not_equal", "500"},
+		{"string", "contains", "science"},
+		{"string", "does_not_contain", "a,b"},
+		{"string", "in", "Tom,Dick,Harry"},
+		{"string", "not_in", "Tom, Dick,"},
+		{"string", "begins_with", "funky"},
+		{"string", "does_not_begin_with", "true"},
+		{"string", "ends_with", "/exception"},
+		{"string", "does_not_end_with", ""},
+		{"float", "less_than", "60"},
+		{"float", "less_than_equal", "60.5"},
+		{"integer", "greater_than", "45"},
+		{"integer", "greater_than_equal", "-45"},
+		{"integer", "between", "500,599"},
+		{"float", "not_between", "1.5, 2"},
+		{"integer", "in", "1,3,45"},
+		{"bool", "equal", "true"},
+		{"bool", "not_equal", "FALSE"},
+		{"string", "any_of", "admin,beta"},
+		{"integer", "contains_all", "200,404"},
+		{"string", "length_greater_than", "2"},
+		{"ip", "in_cidr", "10.0.0.0/8,fd00::/8"},
+	}
+
+	rules := model.Rules{}
+	for _, leaf := range leaves {
+		rules = append(rules, newRuleLeaf("attributes.http#jsonExtract(a, b)", leaf.datatype, leaf.operator, leaf.value))
+	}
+
+	// single rules
+	for _, rule := range rules {
+		assertRoundTrip(t, rule)
+	}
+
+	// nested groups of all sizes
+	and, or, not, nand, nor := model.AND, model.OR, model.NOT, model.NAND, model.NOR
+	rule := model.Rule{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &and, Rules: model.Rules{
+		rules[0],
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &or, Rules: rules[1:4]}},
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &and, Rules: rules[4:6]}},
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &or, Rules: rules[6:7]}},
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &and, Rules: model.Rules{}}},
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &not, Rules: rules[7:8]}},
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &nand, Rules: rules[8:10]}},
+		{Type: model.RULE_GROUP, RuleGroup: &model.RuleGroup{Condition: &nor, Rules: rules[10:13]}},
+	}}}
+	assertRoundTrip(t, rule)
+
+	// odd attribute names and metadata
+	leaf := newRuleLeaf("AND", "string", "equal", "x")
+	assertRoundTrip(t, leaf)
+	leaf = newRuleLeaf("items[0].id:name", "string", "equal", "x")
+	field, input, jsonPath := "req_path", model.InputTypes("string"), []string{"a", "b"}
+	leaf.Field, leaf.Input, leaf.JsonPath = &field, &input, &jsonPath
+	assertRoundTrip(t, leaf)
+	leaf = newRuleLeaf("host", "string", "in", "*.example.com,localhost")
+	leaf.Options = &model.MatchOptions{IgnoreCase: true, Glob: true}
+	assertRoundTrip(t, leaf)
+
+	// rules from a scenario
+	var w model.Workload
+	err := json.Unmarshal(common.GetBytesFromFile("files/unsortedWorkloadJs.json"), &w)
+	assert.NoError(t, err)
+	assertRoundTrip(t, w.Rule)
+}
+
+func assertRoundTrip(t *testing.T, rule model.Rule) {
+	formatted, err := dsl.FormatRule(rule)
+	assert.NoError(t, err)
+
+	parsed, err := dsl.ParseRule(formatted)
+	assert.NoError(t, err, formatted)
+	assert.Equal(t, rule, parsed, formatted)
+}
+
+func newRuleLeaf(id string, datatype string, operator string, value string) model.Rule {
+	dataType, operatorType, valueType := model.DataType(datatype), model.OperatorTypes(operator), model.ValueTypes(value)
+	return model.Rule{Type: model.RULE, RuleLeaf: &model.RuleLeaf{ID: &id, Datatype: &dataType, Operator: &operatorType, Value: &valueType}}
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/validation"
+	"testing"
+)
+
+func TestScenarioSchemaIsUpToDate(t *testing.T) {
+	var schemaFromFile map[string]interface{}
+	err := json.Unmarshal(common.GetBytesFromFile("../scenario/validation/scenario.schema.json"), &schemaFromFile)
+	assert.NoError(t, err)
+
+	// round trip the generated schema through json to compare it with the file
+	var generatedSchema map[string]interface{}
+	schemaJson, err := json.Marshal(validation.ScenarioSchema())
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(schemaJson, &generatedSchema))
+
+	assert.Equal(t, generatedSchema, schemaFromFile, "regenerate scenario.schema.json from validation.ScenarioSchema()")
+}
+
+func TestDecodeScenario(t *testing.T) {
+	for _, path := range []string{"files/validScenarioJsonString.json", "files/scenarioValidation/valid.json"} {
+		scenarioJson := common.GetBytesFromFile(path)
+		decoded, err := validation.DecodeScenario(scenarioJson)
+		assert.NoError(t, err, path)
+
+		var expected model.Scenario
+		assert.NoError(t, json.Unmarshal(scenarioJson, &expected))
+		assert.Equal(t, expected, decoded, path)
+	}
+}
+
+func TestDecodeScenarioRejectsTypos(t *testing.T) {
+	_, err := validation.DecodeScenario(common.GetBytesFromFile("files/strictDecoding/typos.json"))
+	assert.Error(t, err)
+
+	decodeErrors, ok := err.(validation.DecodeErrors)
+	assert.True(t, ok)
+
+	type position struct {
+		Location string
+		Line     int
+		Column   int
+	}
+	positions := make([]position, 0)
+	for _, decodeError := range decodeErrors {
+		positions = append(positions, position{decodeError.Location, decodeError.Line, decodeError.Column})
+	}
+	assert.Equal(t, []position{
+		{"/enabled", 6, 14},
+		{"/workloads/idA/rule/rules/0/datatyp", 18, 13},
+		{"/workloads/idA/rule/rules/0/opertor", 19, 13},
+		{"/workloads/idA/rule/rules/0", 15, 11},
+		{"/workloads/idA/rule/rules/0", 15, 11},
+		{"/filter/condition", 28, 18},
+	}, positions)
+	assert.Equal(t, `unknown property "datatyp"`, decodeErrors[1].Message)
+	assert.Equal(t, `missing required property "datatype"`, decodeErrors[3].Message)
+	assert.Equal(t, `missing required property "operator"`, decodeErrors[4].Message)
+}
+
+func TestDecodeRuleAndFilter(t *testing.T) {
+	rule, err := validation.DecodeRule([]byte(`{"type": "rule", "id": "req_method", "datatype": "string", "operator": "equal", "value": "POST"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "req_method", *rule.ID)
+
+	_, err = validation.DecodeRule([]byte(`{"type": "rule_group", "rules": [{"type": "rule"}]}`))
+	assert.EqualError(t, err, `line 1, column 34 (/rules/0): missing required property "id"; `+
+		`line 1, column 34 (/rules/0): missing required property "datatype"; `+
+		`line 1, column 34 (/rules/0): missing required property "operator"; `+
+		`line 1, column 34 (/rules/0): missing required property "value"; `+
+		`line 1, column 1 (/): missing required property "condition"`)
+
+	filter, err := validation.DecodeFilter([]byte(`{"type": "count", "workload_ids": ["idA"], "min_count": 3}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *filter.MinCount)
+
+	_, err = validation.DecodeFilter([]byte(`{"type": "count", "workload_ids": ["idA"], "min_count": 2.5}`))
+	assert.EqualError(t, err, "line 1, column 57 (/min_count): expected integer, found 2.5")
+
+	// filters built in go decode back to the same filter
+	within := "2s"
+	minCount := 2
+	workloadIds := model.WorkloadIds{"idA", "idB"}
+	for _, filter := range []model.Filter{
+		{Type: model.WORKLOAD, Condition: model.OR, WorkloadIds: &workloadIds},
+		{Type: model.FILTER, Condition: model.AND, Filters: &model.Filters{
+			{Type: model.WORKLOAD, Condition: model.AND, WorkloadIds: &workloadIds},
+			{Type: model.DESCENDANT, WorkloadIds: &workloadIds},
+		}},
+		{Type: model.SEQUENCE, WorkloadIds: &workloadIds, Within: &within},
+		{Type: model.COUNT, WorkloadIds: &workloadIds, MinCount: &minCount},
+		{Type: model.DESCENDANT, WorkloadIds: &workloadIds},
+	} {
+		filterJson, err := json.Marshal(filter)
+		assert.NoError(t, err)
+		decoded, err := validation.DecodeFilter(filterJson)
+		assert.NoError(t, err, string(filterJson))
+		assert.Equal(t, filter, decoded, string(filterJson))
+	}
+
+	_, err = validation.DecodeWorkload([]byte(`{"executor": "OTEL", "rule": {"type": "rule"`))
+	assert.EqualError(t, err, "line 1, column 45 (/): unexpected end of JSON input")
+}
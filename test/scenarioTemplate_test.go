@@ -0,0 +1,66 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/template"
+	"testing"
+)
+
+func TestInstantiateScenarioTemplate(t *testing.T) {
+	var scenarioTemplate model.Scenario
+	err := json.Unmarshal(common.GetBytesFromFile("files/scenarioTemplate/template.json"), &scenarioTemplate)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"namespace", "service", "status"}, template.Variables(scenarioTemplate))
+
+	params := map[string]string{"namespace": "shop", "service": "checkout", "status": "500"}
+	scenario, err := template.Instantiate(scenarioTemplate, params)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "HTTP 500 errors in checkout", scenario.Title)
+	assert.NotEqual(t, scenarioTemplate.Id, scenario.Id)
+	assert.Len(t, *scenario.Workloads, 1)
+	for workloadId, workload := range *scenario.Workloads {
+		assert.Equal(t, model.WorkLoadUUID(workload).String(), workloadId)
+		assert.Equal(t, "shop/checkout", workload.Service)
+		values := make(map[string]model.ValueTypes)
+		for _, rule := range workload.Rule.Rules {
+			values[*rule.ID] = *rule.Value
+		}
+		assert.Equal(t, map[string]model.ValueTypes{"http_status_code": "500", "http_route": "/api/checkout"}, values)
+		assert.Equal(t, []string{workloadId}, []string(*scenario.Filter.WorkloadIds))
+		assert.Equal(t, workloadId, scenario.GroupBy[0].WorkloadId)
+	}
+
+	// the template is not modified
+	assert.Equal(t, "HTTP ${status} errors in ${service}", scenarioTemplate.Title)
+	assert.Contains(t, *scenarioTemplate.Workloads, "client")
+
+	// the same params give the same scenario
+	sameScenario, err := template.Instantiate(scenarioTemplate, map[string]string{"status": "500", "service": "checkout", "namespace": "shop"})
+	assert.NoError(t, err)
+	assert.Equal(t, scenario.Id, sameScenario.Id)
+	assert.Equal(t, scenario.Workloads, sameScenario.Workloads)
+
+	// params which are not variables of the template do not change the scenario
+	extraParams := map[string]string{"namespace": "shop", "service": "checkout", "status": "500", "team": "payments"}
+	sameScenario, err = template.Instantiate(scenarioTemplate, extraParams)
+	assert.NoError(t, err)
+	assert.Equal(t, scenario.Id, sameScenario.Id)
+
+	otherScenario, err := template.Instantiate(scenarioTemplate, map[string]string{"namespace": "shop", "service": "payments", "status": "500"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, scenario.Id, otherScenario.Id)
+	assert.NotEqual(t, scenario.Filter.WorkloadIds, otherScenario.Filter.WorkloadIds)
+}
+
+func TestInstantiateScenarioTemplateMissingParams(t *testing.T) {
+	var scenarioTemplate model.Scenario
+	err := json.Unmarshal(common.GetBytesFromFile("files/scenarioTemplate/template.json"), &scenarioTemplate)
+	assert.NoError(t, err)
+
+	_, err = template.Instantiate(scenarioTemplate, map[string]string{"service": "checkout"})
+	assert.EqualError(t, err, "missing params for template http_errors_template: namespace, status")
+}
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"github.com/zerok-ai/zk-utils-go/common"
+	"github.com/zerok-ai/zk-utils-go/scenario"
 	"github.com/zerok-ai/zk-utils-go/scenario/model"
+	"github.com/zerok-ai/zk-utils-go/scenario/validation"
+	otlpTrace "go.opentelemetry.io/proto/otlp/trace/v1"
 	"log"
 	"testing"
+	"time"
 )
 
 func TestScenarioMarshalUnMarshalSuccess(t *testing.T) {
@@ -60,6 +64,29 @@ func TestSort(t *testing.T) {
 	assert.Equal(t, len(w.Rule.RuleGroup.Rules[3].RuleGroup.Rules), 2)
 	assert.Equal(t, *w.Rule.RuleGroup.Rules[3].RuleGroup.Rules[0].RuleLeaf.ID, "req_method_place_1")
 	assert.Equal(t, *w.Rule.RuleGroup.Rules[3].RuleGroup.Rules[1].RuleLeaf.ID, "req_path_place_2")
+
+	// groups are ordered by their rules unless the condition is smaller, which keeps the WorkLoadUUID of stored workloads
+	var groups model.Rules
+	err = json.Unmarshal([]byte(`[
+		{"type": "rule_group", "condition": "OR", "rules": [
+			{"type": "rule", "id": "a", "datatype": "string", "operator": "equal", "value": "x"}]},
+		{"type": "rule_group", "condition": "AND", "rules": [
+			{"type": "rule", "id": "b", "datatype": "string", "operator": "equal", "value": "y"},
+			{"type": "rule", "id": "c", "datatype": "string", "operator": "equal", "value": "z"}]}
+	]`), &groups)
+	assert.NoError(t, err)
+	assert.True(t, groups[0].RuleGroup.LessThan(*groups[1].RuleGroup))
+
+	// empty match options are the same as no options
+	var leaves model.Rules
+	err = json.Unmarshal([]byte(`[
+		{"type": "rule", "id": "a", "datatype": "string", "operator": "equal", "value": "x", "options": {}},
+		{"type": "rule", "id": "a", "datatype": "string", "operator": "equal", "value": "x"}
+	]`), &leaves)
+	assert.NoError(t, err)
+	assert.True(t, leaves[0].RuleLeaf.Equals(*leaves[1].RuleLeaf))
+	assert.False(t, leaves[0].RuleLeaf.LessThan(*leaves[1].RuleLeaf))
+	assert.False(t, leaves[1].RuleLeaf.LessThan(*leaves[0].RuleLeaf))
 }
 
 func TestScenarioEqualitySuccess(t *testing.T) {
@@ -77,3 +104,256 @@ func TestScenarioEqualitySuccess(t *testing.T) {
 
 	assert.Equal(t, scenario1, scenario2)
 }
+
+func TestSpanFilterEquality(t *testing.T) {
+	newFilter := func(filterType string, within string, minCount int, workloadIds ...string) model.Filter {
+		ids := model.WorkloadIds(workloadIds)
+		filter := model.Filter{Type: filterType, Condition: model.AND, WorkloadIds: &ids}
+		if within != "" {
+			filter.Within = &within
+		}
+		if minCount > 0 {
+			filter.MinCount = &minCount
+		}
+		return filter
+	}
+	siblings := func(reversed bool) model.Filter {
+		filters := model.Filters{
+			newFilter(model.SEQUENCE, "", 0, "idA", "idB"),
+			newFilter(model.SEQUENCE, "", 0, "idB", "idA"),
+			newFilter(model.SEQUENCE, "2s", 0, "idA", "idB"),
+			newFilter(model.COUNT, "", 3, "idC"),
+			newFilter(model.COUNT, "", 2, "idC"),
+			newFilter(model.DESCENDANT, "", 0, "idA", "idC"),
+			newFilter(model.WORKLOAD, "", 0, "idD"),
+		}
+		if reversed {
+			for i, j := 0, len(filters)-1; i < j; i, j = i+1, j-1 {
+				filters[i], filters[j] = filters[j], filters[i]
+			}
+		}
+		return model.Filter{Type: model.FILTER, Condition: model.AND, Filters: &filters}
+	}
+
+	assert.True(t, siblings(false).Equals(siblings(true)))
+	assert.True(t, newFilter(model.COUNT, "", 0, "idA", "idB").Equals(newFilter(model.COUNT, "", 0, "idB", "idA")))
+	assert.False(t, newFilter(model.SEQUENCE, "", 0, "idA", "idB").Equals(newFilter(model.SEQUENCE, "", 0, "idB", "idA")))
+}
+
+func TestValidateScenario(t *testing.T) {
+	var validScenario model.Scenario
+	err := json.Unmarshal(common.GetBytesFromFile("files/scenarioValidation/valid.json"), &validScenario)
+	assert.NoError(t, err)
+	assert.Empty(t, validation.ValidateScenario(validScenario))
+
+	var invalidScenario model.Scenario
+	err = json.Unmarshal(common.GetBytesFromFile("files/scenarioValidation/invalid.json"), &invalidScenario)
+	assert.NoError(t, err)
+
+	locations := make([]string, 0)
+	for _, issue := range validation.ValidateScenario(invalidScenario) {
+		locations = append(locations, issue.Location)
+	}
+	assert.Equal(t, []string{
+		"/workloads/ns~1idA/rule/rules/0/value",
+		"/workloads/ns~1idA/rule/rules/1/value",
+		"/workloads/ns~1idA/rule/rules/2/operator",
+		"/workloads/ns~1idA/rule/rules/3/id",
+		"/workloads/ns~1idA/rule/rules/4/rules",
+		"/workloads/ns~1idA/rule/rules/5/value",
+		"/workloads/ns~1idA/rule/rules/6/options",
+		"/workloads/ns~1idA/rule/rules/7/id",
+		"/filter/workload_ids/1",
+		"/rate_limit/0/tick_duration",
+	}, locations)
+}
+
+func TestFindMatchingScenariosWithNegatedFilters(t *testing.T) {
+	newFilter := func(condition model.Condition, workloadIds ...string) model.Filter {
+		ids := model.WorkloadIds(workloadIds)
+		return model.Filter{Type: model.WORKLOAD, Condition: condition, WorkloadIds: &ids}
+	}
+	scenarios := map[string]*model.Scenario{
+		"not":  {Title: "not", Filter: newFilter(model.NOT, "idA")},
+		"nand": {Title: "nand", Filter: newFilter(model.NAND, "idA", "idB")},
+		"nor":  {Title: "nor", Filter: newFilter(model.NOR, "idB", "idC")},
+		"nested": {Title: "nested", Filter: model.Filter{Type: model.FILTER, Condition: model.AND, Filters: &model.Filters{
+			newFilter(model.AND, "idA"),
+			{Type: model.FILTER, Condition: model.NOT, Filters: &model.Filters{newFilter(model.OR, "idB", "idC")}},
+		}}},
+	}
+
+	matching, err := scenario.FindMatchingScenarios([]string{"idA"}, scenarios)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"nand", "nor", "nested"}, matching)
+
+	matching, err = scenario.FindMatchingScenarios([]string{"idA", "idB"}, scenarios)
+	assert.NoError(t, err)
+	assert.Empty(t, matching)
+
+	// filters without a condition are AND filters, where they used to be satisfied by any workloads
+	scenarios = map[string]*model.Scenario{
+		"empty": {Title: "empty", Filter: model.Filter{Type: model.FILTER, Filters: &model.Filters{newFilter("", "idA", "idB")}}},
+	}
+	matching, err = scenario.FindMatchingScenarios([]string{"idA"}, scenarios)
+	assert.NoError(t, err)
+	assert.Empty(t, matching)
+	matching, err = scenario.FindMatchingScenarios([]string{"idA", "idB"}, scenarios)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"empty"}, matching)
+}
+
+func TestScenarioIndex(t *testing.T) {
+	newFilter := func(condition model.Condition, workloadIds ...string) model.Filter {
+		ids := model.WorkloadIds(workloadIds)
+		return model.Filter{Type: model.WORKLOAD, Condition: condition, WorkloadIds: &ids}
+	}
+	scenarios := map[string]*model.Scenario{
+		"sc1": {Id: "sc1", Title: "errors", Version: "1", Filter: newFilter(model.AND, "idA")},
+		"sc2": {Id: "sc2", Title: "errors", Version: "1", Filter: newFilter(model.OR, "idA", "idB")},
+		"sc3": {Id: "sc3", Title: "no idC", Version: "1", Filter: newFilter(model.NOT, "idC")},
+	}
+	index := scenario.NewScenarioIndex(scenarios)
+
+	assert.Equal(t, []scenario.MatchedScenario{
+		{Id: "sc1", Title: "errors"},
+		{Id: "sc2", Title: "errors"},
+		{Id: "sc3", Title: "no idC"},
+	}, index.FindMatchingScenarios([]string{"idA"}))
+	assert.Equal(t, []scenario.MatchedScenario{{Id: "sc2", Title: "errors"}}, index.FindMatchingScenarios([]string{"idB", "idC"}))
+	assert.Equal(t, []scenario.MatchedScenario{{Id: "sc3", Title: "no idC"}}, index.FindMatchingScenarios([]string{"idD"}))
+
+	// replace sc1, remove sc2 and add sc4
+	refreshed := map[string]*model.Scenario{
+		"sc1": {Id: "sc1", Title: "errors", Version: "2", Filter: newFilter(model.AND, "idB")},
+		"sc3": scenarios["sc3"],
+		"sc4": {Id: "sc4", Title: "idA and idD", Version: "1", Filter: newFilter(model.AND, "idA", "idD")},
+	}
+	index.Refresh(refreshed)
+
+	assert.Equal(t, []scenario.MatchedScenario{{Id: "sc3", Title: "no idC"}}, index.FindMatchingScenarios([]string{"idA"}))
+	assert.Equal(t, []scenario.MatchedScenario{
+		{Id: "sc1", Title: "errors"},
+		{Id: "sc3", Title: "no idC"},
+		{Id: "sc4", Title: "idA and idD"},
+	}, index.FindMatchingScenarios([]string{"idA", "idB", "idD"}))
+}
+
+func TestFindMatchingScenariosForSpans(t *testing.T) {
+	newFilter := func(filterType string, workloadIds ...string) model.Filter {
+		ids := model.WorkloadIds(workloadIds)
+		return model.Filter{Type: filterType, Condition: model.AND, WorkloadIds: &ids}
+	}
+	within := "2s"
+	minCount := 3
+	withinSequence := newFilter(model.SEQUENCE, "idA", "idB")
+	withinSequence.Within = &within
+	minCountFilter := newFilter(model.COUNT, "idC")
+	minCountFilter.MinCount = &minCount
+	minCountWithin := newFilter(model.COUNT, "idC")
+	minCountWithin.MinCount = &minCount
+	minCountWithin.Within = &within
+
+	scenarios := map[string]*model.Scenario{
+		"sequence":           {Title: "sequence", Filter: newFilter(model.SEQUENCE, "idA", "idB")},
+		"reverse sequence":   {Title: "reverse sequence", Filter: newFilter(model.SEQUENCE, "idB", "idA")},
+		"sequence within":    {Title: "sequence within", Filter: withinSequence},
+		"count":              {Title: "count", Filter: minCountFilter},
+		"count within":       {Title: "count within", Filter: minCountWithin},
+		"descendant":         {Title: "descendant", Filter: newFilter(model.DESCENDANT, "idA", "idC")},
+		"reverse descendant": {Title: "reverse descendant", Filter: newFilter(model.DESCENDANT, "idC", "idA")},
+		"mixed": {Title: "mixed", Filter: model.Filter{Type: model.FILTER, Condition: model.AND, Filters: &model.Filters{
+			newFilter(model.WORKLOAD, "idB"),
+			{Type: model.FILTER, Condition: model.NOT, Filters: &model.Filters{newFilter(model.DESCENDANT, "idB", "idC")}},
+		}}},
+	}
+
+	second := uint64(time.Second)
+	spans := []scenario.WorkloadSpan{
+		scenario.NewWorkloadSpan(&otlpTrace.Span{SpanId: []byte{1}, StartTimeUnixNano: 0, EndTimeUnixNano: 10 * second}, []string{"idA"}),
+		scenario.NewWorkloadSpan(&otlpTrace.Span{SpanId: []byte{2}, ParentSpanId: []byte{1}, StartTimeUnixNano: 1 * second, EndTimeUnixNano: 2 * second}, []string{"idC"}),
+		scenario.NewWorkloadSpan(&otlpTrace.Span{SpanId: []byte{3}, ParentSpanId: []byte{2}, StartTimeUnixNano: 3 * second, EndTimeUnixNano: 4 * second}, []string{"idC"}),
+		scenario.NewWorkloadSpan(&otlpTrace.Span{SpanId: []byte{4}, ParentSpanId: []byte{3}, StartTimeUnixNano: 8 * second, EndTimeUnixNano: 9 * second}, []string{"idC"}),
+		scenario.NewWorkloadSpan(&otlpTrace.Span{SpanId: []byte{5}, StartTimeUnixNano: 15 * second, EndTimeUnixNano: 16 * second}, []string{"idB"}),
+	}
+
+	matching, err := scenario.FindMatchingScenariosForSpans(spans, scenarios)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"sequence", "count", "descendant", "mixed"}, matching)
+
+	// workload and filter filters match as they do with FindMatchingScenarios
+	matching, err = scenario.FindMatchingScenarios([]string{"idB"}, scenarios)
+	assert.NoError(t, err)
+	assert.Empty(t, matching)
+
+	// the index matches the span filters only when it is given the spans
+	for key, sc := range scenarios {
+		sc.Id = key
+	}
+	index := scenario.NewScenarioIndex(scenarios)
+	matchingIds := make([]string, 0)
+	for _, matched := range index.FindMatchingScenariosForSpans(spans) {
+		matchingIds = append(matchingIds, matched.Id)
+	}
+	assert.Equal(t, []string{"count", "descendant", "mixed", "sequence"}, matchingIds)
+	assert.Empty(t, index.FindMatchingScenarios([]string{"idA", "idB", "idC"}))
+}
+
+func TestDiffScenarios(t *testing.T) {
+	var oldScenario, newScenario model.Scenario
+	validScenarioJson := common.GetBytesFromFile("files/validScenarioJsonString.json")
+	assert.NoError(t, json.Unmarshal(validScenarioJson, &oldScenario))
+	assert.NoError(t, json.Unmarshal(validScenarioJson, &newScenario))
+
+	diff := scenario.DiffScenarios(oldScenario, newScenario)
+	assert.True(t, diff.IsEmpty())
+
+	newScenario.Version = "23424"
+	newScenario.Title = "sc1 updated"
+
+	// change a rule leaf, add a rule and add a workload
+	workloadA := (*newScenario.Workloads)["idA"]
+	value := model.ValueTypes("/error")
+	workloadA.Rule.Rules[0].RuleLeaf.Value = &value
+	ruleId := "req_method"
+	workloadA.Rule.Rules = append(workloadA.Rule.Rules, model.Rule{Type: model.RULE, RuleLeaf: &model.RuleLeaf{ID: &ruleId}})
+	(*newScenario.Workloads)["idA"] = workloadA
+	(*newScenario.Workloads)["idB"] = model.Workload{Executor: model.ExecutorOTel, Rule: workloadA.Rule}
+
+	// reordering the workload ids does not change the filter
+	filterIds := (*(*newScenario.Filter.Filters)[1].Filters)[1].WorkloadIds
+	(*filterIds)[0], (*filterIds)[1] = (*filterIds)[1], (*filterIds)[0]
+
+	newScenario.GroupBy = []model.GroupBy{{WorkloadId: "idA", Title: "req_path", Hash: "req_path"}}
+	newScenario.RateLimit = []model.RateLimit{{BucketMaxSize: 5, BucketRefillSize: 5, TickDuration: "1m"}}
+
+	diff = scenario.DiffScenarios(oldScenario, newScenario)
+	assert.False(t, diff.IsEmpty())
+	assert.Equal(t, "23423", diff.OldVersion)
+	assert.Equal(t, "23424", diff.NewVersion)
+	assert.Equal(t, []string{"scenario_title"}, diff.ChangedFields)
+	assert.Equal(t, []string{"idB"}, diff.AddedWorkloads)
+	assert.Empty(t, diff.RemovedWorkloads)
+	assert.False(t, diff.FilterChanged)
+	assert.Equal(t, newScenario.GroupBy, diff.AddedGroupBy)
+	assert.Equal(t, newScenario.RateLimit, diff.AddedRateLimits)
+	assert.Equal(t, []string{"idA", "idB"}, diff.AffectedWorkloads())
+
+	assert.Len(t, diff.ChangedWorkloads, 1)
+	ruleChanges := diff.ChangedWorkloads[0].RuleChanges
+	assert.Len(t, ruleChanges, 2)
+	assert.Equal(t, "/rules/0", ruleChanges[0].Path)
+	assert.Equal(t, scenario.RuleChanged, ruleChanges[0].Change)
+	assert.Equal(t, model.ValueTypes("/exception"), *ruleChanges[0].Old.Value)
+	assert.Equal(t, model.ValueTypes("/error"), *ruleChanges[0].New.Value)
+	assert.Equal(t, scenario.RuleAdded, ruleChanges[1].Change)
+	assert.Nil(t, ruleChanges[1].Old)
+
+	// the scenarios are not modified
+	assert.Equal(t, model.WorkloadIds{"id3", "id1"}, *(*(*oldScenario.Filter.Filters)[1].Filters)[1].WorkloadIds)
+
+	// removing the workload is reported the other way round
+	diff = scenario.DiffScenarios(newScenario, oldScenario)
+	assert.Equal(t, []string{"idB"}, diff.RemovedWorkloads)
+	assert.Equal(t, newScenario.RateLimit, diff.RemovedRateLimits)
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/cache"
+	"github.com/zerok-ai/zk-utils-go/scenario/model/evaluators/functions"
+	"github.com/zerok-ai/zk-utils-go/storage/redis/stores"
+)
+
+func TestSqlFunctions(t *testing.T) {
+	ff := functions.NewFunctionFactory(stores.NewMemoryHSetStore(nil), stores.NewMemoryExecutorAttrStore(nil))
+	key, err := cache.ParseKey("OTEL_1.21.0_HTTP")
+	assert.NoError(t, err)
+
+	statements := []struct {
+		query       string
+		operation   string
+		isWrite     bool
+		tables      []interface{}
+		fingerprint string
+	}{
+		{
+			query:       "SELECT o.id, u.name FROM orders o\n  JOIN `shop`.`users` AS u ON u.id = o.user_id WHERE o.total > 10.5 AND u.name = 'O''Brien'",
+			operation:   "SELECT",
+			tables:      []interface{}{"orders", "shop.users"},
+			fingerprint: "SELECT o.id, u.name FROM orders o JOIN `shop`.`users` AS u ON u.id = o.user_id WHERE o.total > ? AND u.name = ?",
+		},
+		{
+			query:       "/* app:checkout */ insert into order_items (order_id, sku) values (42, \"a-1\"), (42, 'b-2') on duplicate key update qty = qty + 1",
+			operation:   "INSERT",
+			isWrite:     true,
+			tables:      []interface{}{"order_items"},
+			fingerprint: "insert into order_items (order_id, sku) values (?) on duplicate key update qty = qty + ?",
+		},
+		{
+			query:       "INSERT INTO tags VALUES (1,'x'),(2,'y'),(3,'z')",
+			operation:   "INSERT",
+			isWrite:     true,
+			tables:      []interface{}{"tags"},
+			fingerprint: "INSERT INTO tags VALUES (?)",
+		},
+		{
+			query:       "UPDATE accounts a, ledgers SET a.balance = a.balance - 5 WHERE a.id IN (1, 2, 3) -- transfer",
+			operation:   "UPDATE",
+			isWrite:     true,
+			tables:      []interface{}{"accounts", "ledgers"},
+			fingerprint: "UPDATE accounts a, ledgers SET a.balance = a.balance - ? WHERE a.id IN (?)",
+		},
+		{
+			query:       "WITH recent AS (SELECT * FROM events WHERE ts > NOW() - INTERVAL 1 DAY) DELETE FROM archive WHERE id IN (SELECT id FROM recent)",
+			operation:   "DELETE",
+			isWrite:     true,
+			tables:      []interface{}{"events", "archive", "recent"},
+			fingerprint: "WITH recent AS (SELECT * FROM events WHERE ts > NOW() - INTERVAL ? DAY) DELETE FROM archive WHERE id IN (SELECT id FROM recent)",
+		},
+		{
+			query:       "CREATE TABLE IF NOT EXISTS audit_log (id INT)",
+			operation:   "CREATE",
+			isWrite:     true,
+			tables:      []interface{}{"audit_log"},
+			fingerprint: "CREATE TABLE IF NOT EXISTS audit_log (id INT)",
+		},
+		{
+			query:       "select * from (select id from users # inline\n) t for update",
+			operation:   "SELECT",
+			tables:      []interface{}{"users"},
+			fingerprint: "select * from (select id from users ) t for update",
+		},
+	}
+
+	for _, statement := range statements {
+		valueStore := map[string]interface{}{"query": statement.query}
+
+		value, ok := ff.EvaluateString("query#sqlOperation()", valueStore, &key)
+		assert.True(t, ok, statement.query)
+		assert.Equal(t, statement.operation, value, statement.query)
+
+		value, ok = ff.EvaluateString("query#sqlIsWrite()", valueStore, &key)
+		assert.True(t, ok, statement.query)
+		assert.Equal(t, statement.isWrite, value, statement.query)
+
+		value, ok = ff.EvaluateString("query#sqlTables()", valueStore, &key)
+		assert.True(t, ok, statement.query)
+		assert.Equal(t, statement.tables, value, statement.query)
+
+		value, ok = ff.EvaluateString("query#sqlFingerprint()", valueStore, &key)
+		assert.True(t, ok, statement.query)
+		assert.Equal(t, statement.fingerprint, value, statement.query)
+	}
+
+	// inserts of one or more rows have the same fingerprint
+	for _, query := range []string{"INSERT INTO tags VALUES (1,'x')", "INSERT INTO tags VALUES (1,'x'), (2,'y')", "INSERT INTO tags VALUES (1),(2)"} {
+		value, ok := ff.EvaluateString("query#sqlFingerprint()", map[string]interface{}{"query": query}, &key)
+		assert.True(t, ok, query)
+		assert.Equal(t, "INSERT INTO tags VALUES (?)", value, query)
+	}
+
+	_, ok := ff.EvaluateString("query#sqlOperation()", map[string]interface{}{"query": " -- nothing"}, &key)
+	assert.False(t, ok)
+}
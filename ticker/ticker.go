@@ -2,6 +2,7 @@ package zktick
 
 import (
 	zklogger "github.com/zerok-ai/zk-utils-go/logs"
+	"sync"
 	"time"
 )
 
@@ -14,6 +15,10 @@ type TickerTask struct {
 	task     func()
 	counter  int
 	interval time.Duration
+
+	// done is closed by Stop to end the goroutine of the task
+	done     chan struct{}
+	stopOnce *sync.Once
 }
 
 func GetNewTickerTask(name string, interval time.Duration, task func()) *TickerTask {
@@ -22,6 +27,8 @@ func GetNewTickerTask(name string, interval time.Duration, task func()) *TickerT
 		task:     task,
 		name:     name,
 		interval: interval,
+		done:     make(chan struct{}),
+		stopOnce: &sync.Once{},
 	}
 }
 
@@ -31,6 +38,8 @@ func (tt TickerTask) Start() *TickerTask {
 		time.AfterFunc(tt.interval, func() {
 			for {
 				select {
+				case <-tt.done:
+					return
 				case <-tt.ticker.C:
 					// Perform the task
 					zklogger.DebugF(LogTag, "tick (%s) - %d\n", tt.name, tt.counter)
@@ -54,5 +63,8 @@ func runTaskAndFlushPendingTasks(tt TickerTask) {
 
 func (tt TickerTask) Stop() *TickerTask {
 	tt.ticker.Stop()
+	tt.stopOnce.Do(func() {
+		close(tt.done)
+	})
 	return &tt
 }